@@ -0,0 +1,207 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const flashCookieName = "_flash"
+
+type ActionHandler func(ctx *ActionContext) error
+
+type PageAction struct {
+	Handler    ActionHandler
+	Middleware *MiddlewareChain
+}
+
+type ActionContext struct {
+	Request    *http.Request
+	Writer     http.ResponseWriter
+	Method     string
+	Params     map[string]string
+	Form       url.Values
+	Errors     map[string]string
+	Config     *Config
+	redirectTo string
+	flash      string
+}
+
+type actionWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+var actionMethods = map[string]bool{
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+func (r *Router) Action(route string, handler ActionHandler, middleware ...MiddlewareFunc) {
+	mc := NewMiddlewareChain()
+	for _, m := range middleware {
+		mc.Use(m)
+	}
+
+	r.Actions[normalizePath(route)] = PageAction{
+		Handler:    handler,
+		Middleware: mc,
+	}
+}
+
+func (ctx *ActionContext) Value(name string) string {
+	return ctx.Form.Get(name)
+}
+
+func (ctx *ActionContext) AddError(field, message string) {
+	ctx.Errors[field] = message
+}
+
+func (ctx *ActionContext) HasErrors() bool {
+	return len(ctx.Errors) > 0
+}
+
+func (ctx *ActionContext) Redirect(url string) {
+	ctx.redirectTo = url
+}
+
+func (ctx *ActionContext) Flash(message string) {
+	ctx.flash = message
+}
+
+func (aw *actionWriter) WriteHeader(status int) {
+	aw.wrote = true
+	aw.ResponseWriter.WriteHeader(status)
+}
+
+func (aw *actionWriter) Write(p []byte) (int, error) {
+	aw.wrote = true
+	return aw.ResponseWriter.Write(p)
+}
+
+func (r *Router) serveAction(w http.ResponseWriter, req *http.Request, route string) {
+	if err := parseActionForm(req); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	method := req.Method
+	if method == http.MethodPost {
+		if override := strings.ToUpper(req.PostForm.Get("_method")); override != "" && override != http.MethodPost {
+			method = override
+		}
+	}
+
+	action, ok := r.Actions[route]
+	if !ok || !actionMethods[method] {
+		allow := "GET, HEAD"
+		if ok {
+			allow += ", POST, PUT, PATCH, DELETE"
+		}
+		w.Header().Set("Allow", allow)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		aw := &actionWriter{ResponseWriter: w}
+		ctx := &ActionContext{
+			Request: req,
+			Writer:  aw,
+			Method:  method,
+			Params:  extractParamsFromRequest(req.URL.Path, route),
+			Form:    req.Form,
+			Errors:  make(map[string]string),
+			Config:  &AppConfig,
+		}
+		ctx.Form.Del("_method")
+		ctx.Form.Del(AppConfig.CSRFFieldName)
+
+		if err := action.Handler(ctx); err != nil {
+			r.Logger.ErrorLog.Printf("Action error for %s %s: %v", method, route, err)
+			if !aw.wrote {
+				r.serveErrorPage(w, req, http.StatusInternalServerError)
+			}
+			return
+		}
+		if aw.wrote {
+			return
+		}
+
+		if ctx.HasErrors() {
+			pageCtx := newRouteContext(req, ctx.Params)
+			pageCtx.Form = ctx.Form
+			pageCtx.Errors = ctx.Errors
+			r.renderPage(w, req, route, pageCtx, http.StatusUnprocessableEntity)
+			return
+		}
+
+		target := ctx.redirectTo
+		if target == "" {
+			target = req.URL.Path
+		}
+		if ctx.flash != "" {
+			setFlash(w, req, ctx.flash)
+		}
+		http.Redirect(w, req, target, http.StatusSeeOther)
+	})
+
+	action.Middleware.Then(handler).ServeHTTP(w, req)
+}
+
+func parseActionForm(req *http.Request) error {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		return req.ParseMultipartForm(32 << 20)
+	}
+	return req.ParseForm()
+}
+
+func setFlash(w http.ResponseWriter, req *http.Request, message string) {
+	if session := GetSession(req); session != nil {
+		session.AddFlash(message)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Value:    url.QueryEscape(message),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func hasFlash(req *http.Request) bool {
+	if session := GetSession(req); session != nil && session.HasFlashes() {
+		return true
+	}
+	_, err := req.Cookie(flashCookieName)
+	return err == nil
+}
+
+func consumeFlash(w http.ResponseWriter, req *http.Request) string {
+	if session := GetSession(req); session != nil && session.HasFlashes() {
+		return strings.Join(session.PopFlashes(), " ")
+	}
+
+	cookie, err := req.Cookie(flashCookieName)
+	if err != nil {
+		return ""
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	message, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return message
+}
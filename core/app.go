@@ -1,7 +1,9 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net"
 	"net/http"
@@ -25,11 +27,7 @@ type GonAirApp struct {
 }
 
 func NewApp() *GonAirApp {
-	logger := &AppLogger{
-		InfoLog:  log.New(os.Stdout, "✈️ \033[36mINFO\033[0m  ", log.Ldate|log.Ltime),
-		ErrorLog: log.New(os.Stderr, "✈️ \033[31mERROR\033[0m ", log.Ldate|log.Ltime),
-		WarnLog:  log.New(os.Stdout, "✈️ \033[33mWARN\033[0m  ", log.Ldate|log.Ltime),
-	}
+	logger := newAppLogger()
 
 	router := NewRouter(logger)
 
@@ -40,6 +38,14 @@ func NewApp() *GonAirApp {
 	}
 }
 
+func newAppLogger() *AppLogger {
+	return &AppLogger{
+		InfoLog:  log.New(os.Stdout, "✈️ \033[36mINFO\033[0m  ", log.Ldate|log.Ltime),
+		ErrorLog: log.New(os.Stderr, "✈️ \033[31mERROR\033[0m ", log.Ldate|log.Ltime),
+		WarnLog:  log.New(os.Stdout, "✈️ \033[33mWARN\033[0m  ", log.Ldate|log.Ltime),
+	}
+}
+
 func (app *GonAirApp) Init() error {
 	startTime := time.Now()
 
@@ -55,6 +61,38 @@ func (app *GonAirApp) Init() error {
 	}
 	app.Logger.InfoLog.Printf("Routes initialized successfully")
 
+	if app.Config.Precompress {
+		app.precompressStatic()
+	}
+
+	if err := validateJWTConfig(); err != nil {
+		app.Logger.ErrorLog.Printf("Invalid JWT configuration: %v", err)
+		return fmt.Errorf("invalid JWT configuration: %w", err)
+	}
+
+	if err := validateSessionConfig(); err != nil {
+		app.Logger.ErrorLog.Printf("Invalid session configuration: %v", err)
+		return fmt.Errorf("invalid session configuration: %w", err)
+	}
+
+	if err := validateRateLimitConfig(app.Logger); err != nil {
+		app.Logger.ErrorLog.Printf("Invalid rate limit configuration: %v", err)
+		return fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+
+	if err := validateTrustedProxies(); err != nil {
+		app.Logger.ErrorLog.Printf("Invalid trusted proxies: %v", err)
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	if app.Config.Sitemap && app.Config.BaseURL == "" {
+		app.Logger.WarnLog.Printf("Sitemap is enabled but BaseURL is empty: sitemap.xml is only served in dev mode")
+	}
+
+	if len(app.Config.TrustedProxies) > 0 {
+		app.Router.Use(TrustedProxyMiddleware(nil, app.Logger))
+	}
+
 	
 	configureMiddleware := app.getConfigureMiddlewareFunc()
 	if configureMiddleware != nil {
@@ -62,6 +100,21 @@ func (app *GonAirApp) Init() error {
 		app.Logger.InfoLog.Printf("Middleware configured successfully")
 	}
 
+	if app.Config.EnableRateLimit {
+		app.Router.guard(RateLimiterMiddleware(nil, app.Logger))
+	}
+
+	if app.Config.EnableCSRF {
+		app.Router.guard(CSRFMiddleware(app.Logger))
+	}
+
+	if defaultCredentialsUsed.Load() {
+		if _, err := defaultCredentialStore(app.Logger); err != nil {
+			app.Logger.ErrorLog.Printf("Invalid credentials: %v", err)
+			return fmt.Errorf("invalid credentials: %w", err)
+		}
+	}
+
 	if app.Config.DevMode && app.Config.LiveReload {
 		watcher, err := NewFileWatcher(app.Router, app.Logger)
 		if err != nil {
@@ -78,9 +131,25 @@ func (app *GonAirApp) Init() error {
 	return nil
 }
 
+func (app *GonAirApp) precompressStatic() {
+	if app.Config.UsesEmbeddedFS() {
+		app.Logger.WarnLog.Printf("Skipping precompression: static files are embedded in the binary")
+		return
+	}
+
+	for _, mount := range app.Router.StaticMounts() {
+		count, err := PrecompressDir(mount.Dir)
+		if err != nil {
+			app.Logger.ErrorLog.Printf("Failed to precompress %s: %v", mount.Dir, err)
+			continue
+		}
+		app.Logger.InfoLog.Printf("Precompressed %d files in %s", count, mount.Dir)
+	}
+}
+
 func (app *GonAirApp) getConfigureMiddlewareFunc() func(*GonAirApp) {
-	middlewareConfigPath := filepath.Join(app.Config.AppDir, "middleware.go")
-	if _, err := os.Stat(middlewareConfigPath); os.IsNotExist(err) {
+	middlewareConfigPath := fsPath(filepath.Join(app.Config.AppDir, "middleware.go"))
+	if _, err := fs.Stat(app.Config.SiteFS(), middlewareConfigPath); errors.Is(err, fs.ErrNotExist) {
 		app.Logger.WarnLog.Printf("Middleware configuration file not found at %s", middlewareConfigPath)
 		return nil
 	}
@@ -105,10 +174,21 @@ func (app *GonAirApp) getConfigureMiddlewareFunc() func(*GonAirApp) {
 }
 
 func (app *GonAirApp) Start() error {
+	if app.FileWatcher == nil && hasWatchedFiles() {
+		watcher, err := NewFileWatcher(app.Router, app.Logger)
+		if err != nil {
+			app.Logger.ErrorLog.Printf("Failed to create file watcher: %v", err)
+		} else {
+			app.FileWatcher = watcher
+		}
+	}
+
 	if app.FileWatcher != nil {
 		app.FileWatcher.Start()
 		defer app.FileWatcher.Stop()
-		app.Logger.InfoLog.Printf("Live reload enabled - watching for file changes")
+		if len(app.FileWatcher.dirs) > 0 {
+			app.Logger.InfoLog.Printf("Live reload enabled - watching for file changes")
+		}
 	}
 
 	port := app.Config.Port
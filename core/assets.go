@@ -0,0 +1,144 @@
+package core
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"strings"
+)
+
+const (
+	AssetScript = "script"
+	AssetStyle  = "style"
+)
+
+type Asset struct {
+	Name          string
+	Type          string
+	CDN           string
+	Integrity     string
+	Local         string
+	SkipIntegrity bool
+}
+
+type assetTags struct {
+	order []string
+	tags  map[string]template.HTML
+}
+
+func (c *Config) AssetList() []Asset {
+	assets := []Asset{
+		{
+			Name:          "tailwind",
+			Type:          AssetScript,
+			CDN:           c.TailwindCDN,
+			Local:         c.TailwindLocal,
+			SkipIntegrity: true,
+		},
+		{
+			Name:      "jquery",
+			Type:      AssetScript,
+			CDN:       c.JQueryCDN,
+			Integrity: c.JQueryIntegrity,
+			Local:     c.JQueryLocal,
+		},
+		{
+			Name:      "animate.css",
+			Type:      AssetStyle,
+			CDN:       c.AnimateCSSCDN,
+			Integrity: c.AnimateCSSIntegrity,
+			Local:     c.AnimateCSSLocal,
+		},
+	}
+
+	return append(assets, c.Assets...)
+}
+
+func (m *Marley) buildAssetTags(manifest *AssetManifest) *assetTags {
+	staticFS, err := AppConfig.SubFS(AppConfig.StaticDir)
+	if err != nil {
+		m.Logger.WarnLog.Printf("Failed to open static directory for assets: %v", err)
+	}
+
+	result := &assetTags{tags: make(map[string]template.HTML)}
+	for _, asset := range AppConfig.AssetList() {
+		var localContent []byte
+		if asset.Local != "" && staticFS != nil {
+			localContent, _ = fs.ReadFile(staticFS, asset.Local)
+		}
+
+		url, integrity := asset.CDN, asset.Integrity
+		if asset.SkipIntegrity {
+			integrity = ""
+		}
+		if !AppConfig.DefaultCDNs || asset.CDN == "" {
+			if localContent != nil {
+				url = manifest.URL(asset.Local)
+				integrity = ""
+			} else if asset.CDN != "" && asset.Local == "" {
+				m.Logger.WarnLog.Printf("Asset %q has no local copy configured, using CDN", asset.Name)
+			} else if asset.CDN != "" {
+				m.Logger.WarnLog.Printf("Local copy of asset %q not found in %s/%s, using CDN",
+					asset.Name, AppConfig.StaticDir, asset.Local)
+			} else {
+				m.Logger.WarnLog.Printf("Asset %q has neither a CDN URL nor a local copy", asset.Name)
+			}
+		}
+
+		result.order = append(result.order, asset.Name)
+		if url == "" {
+			result.tags[asset.Name] = ""
+			continue
+		}
+
+		if url == asset.CDN && integrity == "" && !asset.SkipIntegrity && localContent != nil {
+			integrity = subresourceIntegrity(localContent)
+		}
+		if url == asset.CDN && integrity == "" && !asset.SkipIntegrity {
+			m.Logger.WarnLog.Printf("Asset %q is loaded from %s without an integrity hash", asset.Name, url)
+		}
+
+		result.tags[asset.Name] = assetTag(asset.Type, url, integrity)
+	}
+
+	return result
+}
+
+func (a *assetTags) render(names ...string) (template.HTML, error) {
+	if len(names) == 0 {
+		names = a.order
+	}
+
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tag, ok := a.tags[name]
+		if !ok {
+			return "", fmt.Errorf("unknown asset %q", name)
+		}
+		if tag != "" {
+			tags = append(tags, string(tag))
+		}
+	}
+
+	return template.HTML(strings.Join(tags, "\n    ")), nil
+}
+
+func assetTag(assetType, url, integrity string) template.HTML {
+	var attrs strings.Builder
+	if integrity != "" {
+		fmt.Fprintf(&attrs, ` integrity="%s" crossorigin="anonymous"`, html.EscapeString(integrity))
+	}
+
+	if assetType == AssetStyle {
+		return template.HTML(fmt.Sprintf(`<link rel="stylesheet" href="%s"%s>`, html.EscapeString(url), attrs.String()))
+	}
+	return template.HTML(fmt.Sprintf(`<script src="%s"%s></script>`, html.EscapeString(url), attrs.String()))
+}
+
+func subresourceIntegrity(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/js"
+)
+
+const base64VLQChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+type Bundle struct {
+	Name   string
+	Inputs []string
+}
+
+type BuiltBundle struct {
+	Name      string
+	Content   []byte
+	SourceMap []byte
+	Encoded   map[string][]byte
+	Inputs    []string
+	ModTime   time.Time
+}
+
+var bundleMinifier = func() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("application/javascript", js.Minify)
+	return m
+}()
+
+func BuildBundles(staticFS fs.FS, bundles []Bundle, dev bool) (map[string]*BuiltBundle, error) {
+	built := make(map[string]*BuiltBundle)
+
+	for _, bundle := range bundles {
+		name := strings.TrimPrefix(path.Clean("/"+bundle.Name), "/")
+
+		mediaType := ""
+		separator := "\n"
+		switch path.Ext(name) {
+		case ".css":
+			mediaType = "text/css"
+		case ".js":
+			mediaType = "application/javascript"
+			separator = "\n;\n"
+		default:
+			return nil, fmt.Errorf("bundle %s: unsupported extension %q", name, path.Ext(name))
+		}
+
+		inputs, err := expandBundleInputs(staticFS, bundle.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("bundle %s: %w", name, err)
+		}
+		if len(inputs) == 0 {
+			return nil, fmt.Errorf("bundle %s: no input files matched", name)
+		}
+
+		var (
+			content    bytes.Buffer
+			sourceMap  = newSourceMapBuilder(name)
+			latestTime time.Time
+		)
+
+		for i, input := range inputs {
+			data, err := fs.ReadFile(staticFS, input)
+			if err != nil {
+				return nil, fmt.Errorf("bundle %s: failed to read %s: %w", name, input, err)
+			}
+
+			if info, err := fs.Stat(staticFS, input); err == nil && info.ModTime().After(latestTime) {
+				latestTime = info.ModTime()
+			}
+
+			if i > 0 {
+				content.WriteString(separator)
+				sourceMap.skipLines(strings.Count(separator, "\n"))
+			}
+
+			text := strings.TrimRight(string(data), "\n")
+			content.WriteString(text)
+			sourceMap.addSource("/static/"+input, string(data), strings.Count(text, "\n")+1)
+		}
+		content.WriteString("\n")
+
+		result := &BuiltBundle{
+			Name:    name,
+			Inputs:  inputs,
+			ModTime: latestTime,
+		}
+
+		if dev {
+			mapJSON, err := sourceMap.JSON()
+			if err != nil {
+				return nil, fmt.Errorf("bundle %s: failed to build source map: %w", name, err)
+			}
+
+			mapURL := path.Base(name) + ".map"
+			if mediaType == "text/css" {
+				fmt.Fprintf(&content, "/*# sourceMappingURL=%s */\n", mapURL)
+			} else {
+				fmt.Fprintf(&content, "//# sourceMappingURL=%s\n", mapURL)
+			}
+
+			result.Content = content.Bytes()
+			result.SourceMap = mapJSON
+		} else {
+			minified, err := bundleMinifier.Bytes(mediaType, content.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("bundle %s: failed to minify: %w", name, err)
+			}
+			result.Content = minified
+
+			if result.Encoded, err = compressAll(minified); err != nil {
+				return nil, fmt.Errorf("bundle %s: %w", name, err)
+			}
+		}
+
+		built[name] = result
+	}
+
+	return built, nil
+}
+
+func expandBundleInputs(staticFS fs.FS, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var inputs []string
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(path.Clean("/"+pattern), "/")
+
+		matches, err := fs.Glob(staticFS, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("input %q not found", pattern)
+		}
+
+		sort.Strings(matches)
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				inputs = append(inputs, match)
+			}
+		}
+	}
+
+	return inputs, nil
+}
+
+type sourceMapBuilder struct {
+	file           string
+	sources        []string
+	sourcesContent []string
+	mappings       strings.Builder
+	lines          int
+	prevSource     int
+	prevLine       int
+}
+
+func newSourceMapBuilder(file string) *sourceMapBuilder {
+	return &sourceMapBuilder{file: path.Base(file)}
+}
+
+func (b *sourceMapBuilder) skipLines(n int) {
+	for i := 0; i < n; i++ {
+		b.newLine()
+	}
+}
+
+func (b *sourceMapBuilder) addSource(name, content string, lines int) {
+	sourceIndex := len(b.sources)
+	b.sources = append(b.sources, name)
+	b.sourcesContent = append(b.sourcesContent, content)
+
+	for line := 0; line < lines; line++ {
+		if line > 0 {
+			b.newLine()
+		}
+
+		b.mappings.WriteString(encodeVLQ(0))
+		b.mappings.WriteString(encodeVLQ(sourceIndex - b.prevSource))
+		b.mappings.WriteString(encodeVLQ(line - b.prevLine))
+		b.mappings.WriteString(encodeVLQ(0))
+
+		b.prevSource = sourceIndex
+		b.prevLine = line
+	}
+}
+
+func (b *sourceMapBuilder) newLine() {
+	b.mappings.WriteByte(';')
+	b.lines++
+}
+
+func (b *sourceMapBuilder) JSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"version":        3,
+		"file":           b.file,
+		"sources":        b.sources,
+		"sourcesContent": b.sourcesContent,
+		"names":          []string{},
+		"mappings":       b.mappings.String(),
+	})
+}
+
+func encodeVLQ(value int) string {
+	vlq := value << 1
+	if value < 0 {
+		vlq = (-value << 1) | 1
+	}
+
+	var sb strings.Builder
+	for {
+		digit := vlq & 31
+		vlq >>= 5
+		if vlq > 0 {
+			digit |= 32
+		}
+		sb.WriteByte(base64VLQChars[digit])
+		if vlq == 0 {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,526 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+const (
+	CheckError   = "error"
+	CheckWarning = "warning"
+)
+
+var frontMatterLineRegex = regexp.MustCompile(`on line (\d+)`)
+
+var builtinTemplateFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or", "print", "printf", "println",
+	"urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+type CheckProblem struct {
+	File     string
+	Line     int
+	Severity string
+	Message  string
+}
+
+func (p CheckProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Message)
+}
+
+type CheckReport struct {
+	Files    int
+	Problems []CheckProblem
+}
+
+func (r *CheckReport) add(file string, line int, severity, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, CheckProblem{
+		File:     file,
+		Line:     line,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *CheckReport) Errors() int {
+	count := 0
+	for _, problem := range r.Problems {
+		if problem.Severity == CheckError {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *CheckReport) Warnings() int {
+	return len(r.Problems) - r.Errors()
+}
+
+type checkedFile struct {
+	path  string
+	name  string
+	trees map[string]*parse.Tree
+}
+
+func (f *checkedFile) defines() []string {
+	names := make([]string, 0, len(f.trees)+1)
+	for name := range f.trees {
+		if name != f.path {
+			names = append(names, name)
+		}
+	}
+	if f.name != "" {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+func CheckApp() (*CheckReport, error) {
+	siteFS := AppConfig.SiteFS()
+	report := &CheckReport{}
+
+	layoutPath := fsPath(AppConfig.LayoutPath)
+	markdownLayoutPath := ""
+	if AppConfig.MarkdownLayout != "" && fsPath(AppConfig.MarkdownLayout) != layoutPath {
+		markdownLayoutPath = fsPath(AppConfig.MarkdownLayout)
+	}
+
+	var layout, markdownLayout *checkedFile
+	for _, p := range []string{layoutPath, markdownLayoutPath} {
+		if p == "" {
+			continue
+		}
+		content, err := fs.ReadFile(siteFS, p)
+		if err != nil {
+			report.add(p, 0, CheckError, "failed to read layout: %v", err)
+			continue
+		}
+		file := parseCheckedFile(report, p, "", content)
+		if p == layoutPath {
+			layout = file
+		} else {
+			markdownLayout = file
+		}
+	}
+	if markdownLayout == nil && markdownLayoutPath == "" {
+		markdownLayout = layout
+	}
+
+	var components []*checkedFile
+	componentDir := fsPath(AppConfig.ComponentDir)
+	err := fs.WalkDir(siteFS, componentDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+
+		content, err := fs.ReadFile(siteFS, p)
+		if err != nil {
+			report.add(p, 0, CheckError, "failed to read component: %v", err)
+			return nil
+		}
+
+		name := strings.TrimSuffix(path.Base(p), ".html")
+		if file := parseCheckedFile(report, p, name, content); file != nil {
+			components = append(components, file)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to scan components: %w", err)
+	}
+
+	var (
+		pages      []*checkedFile
+		pageFiles  []string
+		routeFiles = make(map[string][]string)
+	)
+	err = fs.WalkDir(siteFS, fsPath(AppConfig.AppDir), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isTemplateFile(p) || p == layoutPath || p == markdownLayoutPath ||
+			fsPathHasPrefix(p, AppConfig.ComponentDir) {
+			return nil
+		}
+
+		pageFiles = append(pageFiles, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan pages: %w", err)
+	}
+
+	frontMatterLayouts := markdownLayoutFiles(siteFS, pageFiles)
+	checkedPages := pageFiles[:0]
+	for _, p := range pageFiles {
+		if frontMatterLayouts[p] {
+			continue
+		}
+		checkedPages = append(checkedPages, p)
+		route := getRoutePathFromFile(p, AppConfig.AppDir)
+		routeFiles[route] = append(routeFiles[route], p)
+	}
+	pageFiles = checkedPages
+
+	hasMarkdown := false
+	markdownLayouts := make(map[string]*checkedFile)
+	sort.Strings(pageFiles)
+	for _, p := range pageFiles {
+		content, err := fs.ReadFile(siteFS, p)
+		if err != nil {
+			report.add(p, 0, CheckError, "failed to read page: %v", err)
+			continue
+		}
+
+		if filepath.Ext(p) == ".md" {
+			page, err := RenderMarkdown(content)
+			if err != nil {
+				report.add(p, frontMatterErrorLine(err), CheckError, "%v", err)
+				continue
+			}
+
+			layout := page.FrontMatter["layout"]
+			if layout == "" {
+				hasMarkdown = true
+				continue
+			}
+			layoutFile := markdownLayoutFile(layout)
+			if _, ok := markdownLayouts[layoutFile]; ok {
+				continue
+			}
+			layoutContent, err := fs.ReadFile(siteFS, layoutFile)
+			if err != nil {
+				report.add(p, frontMatterKeyLine(content, "layout"), CheckError, "failed to read layout %s: %v", layout, err)
+				continue
+			}
+			markdownLayouts[layoutFile] = parseCheckedFile(report, layoutFile, "", layoutContent)
+			continue
+		}
+
+		_, body, err := splitPageFrontMatter(content)
+		if err != nil {
+			report.add(p, 1, CheckError, "invalid front matter: %v", err)
+			continue
+		}
+
+		if file := parseCheckedFile(report, p, "", body); file != nil {
+			pages = append(pages, file)
+		}
+	}
+
+	layouts := make(map[*checkedFile]bool)
+	for _, file := range []*checkedFile{layout, markdownLayout} {
+		if file != nil {
+			layouts[file] = true
+		}
+	}
+	for _, file := range markdownLayouts {
+		if file != nil {
+			layouts[file] = true
+		}
+	}
+	report.Files = len(layouts) + len(components) + len(pageFiles)
+
+	shared := make(map[string]bool)
+	for file := range layouts {
+		for _, name := range file.defines() {
+			shared[name] = true
+		}
+	}
+	for _, component := range components {
+		for _, name := range component.defines() {
+			shared[name] = true
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, component := range components {
+		checkTemplateRefs(report, component, shared, used, "")
+	}
+
+	if hasMarkdown && markdownLayout != nil {
+		defined := withNames(shared, "content", "head")
+		checkTemplateRefs(report, markdownLayout, defined, used, " for markdown pages")
+	}
+	for _, file := range markdownLayouts {
+		if file != nil {
+			defined := withNames(shared, "content", "head")
+			checkTemplateRefs(report, file, defined, used, " for markdown pages")
+		}
+	}
+
+	for _, page := range pages {
+		defined := withNames(shared, page.defines()...)
+
+		checkTemplateRefs(report, page, defined, used, "")
+		if layout != nil {
+			defined["content"] = true
+			checkTemplateRefs(report, layout, defined, used, " when rendering "+page.path)
+		}
+
+		if _, ok := page.trees["content"]; !ok {
+			report.add(page.path, 1, CheckError, "page does not define a \"content\" block")
+		}
+
+		route := getRoutePathFromFile(page.path, AppConfig.AppDir)
+		checkParamRefs(report, page, route)
+	}
+
+	for _, component := range components {
+		isUsed := false
+		for _, name := range component.defines() {
+			if used[name] {
+				isUsed = true
+				break
+			}
+		}
+		if !isUsed {
+			report.add(component.path, 1, CheckWarning, "component %q is never used", component.name)
+		}
+	}
+
+	checkRouteConflicts(report, routeFiles)
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		a, b := report.Problems[i], report.Problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	return report, nil
+}
+
+func parseCheckedFile(report *CheckReport, file, name string, content []byte) *checkedFile {
+	trees := make(map[string]*parse.Tree)
+
+	funcs := make(map[string]interface{})
+	for _, name := range builtinTemplateFuncs {
+		funcs[name] = true
+	}
+	for name, fn := range templateFuncs(nil, nil, nil, nil) {
+		funcs[name] = fn
+	}
+
+	tree := parse.New(file)
+	if _, err := tree.Parse(string(content), "", "", trees, funcs); err != nil {
+		report.add(file, parseErrorLine(file, err), CheckError, "parse error: %v", err)
+		return nil
+	}
+
+	return &checkedFile{path: file, name: name, trees: trees}
+}
+
+func parseErrorLine(file string, err error) int {
+	rest, ok := strings.CutPrefix(err.Error(), "template: "+file+":")
+	if !ok {
+		return 0
+	}
+	digits, _, _ := strings.Cut(rest, ":")
+	line, _ := strconv.Atoi(digits)
+	return line
+}
+
+func frontMatterErrorLine(err error) int {
+	match := frontMatterLineRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 1
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
+
+func frontMatterKeyLine(content []byte, key string) int {
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if i > 0 && strings.TrimSpace(line) == "---" {
+			break
+		}
+		if name, _, found := strings.Cut(line, ":"); found && strings.EqualFold(strings.TrimSpace(name), key) {
+			return i + 1
+		}
+	}
+	return 1
+}
+
+func withNames(names map[string]bool, extra ...string) map[string]bool {
+	merged := make(map[string]bool, len(names)+len(extra))
+	for name := range names {
+		merged[name] = true
+	}
+	for _, name := range extra {
+		merged[name] = true
+	}
+	return merged
+}
+
+func checkTemplateRefs(report *CheckReport, file *checkedFile, defined, used map[string]bool, context string) {
+	for _, tree := range file.trees {
+		walkTemplateNodes(tree.Root, func(n parse.Node) {
+			ref, ok := n.(*parse.TemplateNode)
+			if !ok {
+				return
+			}
+
+			used[ref.Name] = true
+			if !defined[ref.Name] {
+				report.add(file.path, nodeLine(tree, n), CheckError, "template %q is not defined%s", ref.Name, context)
+			}
+		})
+	}
+}
+
+func checkParamRefs(report *CheckReport, page *checkedFile, route string) {
+	allowed := make(map[string]bool)
+	for _, match := range paramRegex.FindAllStringSubmatch(route, -1) {
+		allowed[match[1]] = true
+	}
+	if isErrorPageRoute(route) {
+		allowed["status"] = true
+		allowed["path"] = true
+	}
+
+	for _, tree := range page.trees {
+		walkTemplateNodes(tree.Root, func(n parse.Node) {
+			for _, name := range paramRefs(n) {
+				if !allowed[name] {
+					report.add(page.path, nodeLine(tree, n), CheckError,
+						".Params.%s does not match a [param] in route %s", name, route)
+				}
+			}
+		})
+	}
+}
+
+func paramRefs(n parse.Node) []string {
+	switch node := n.(type) {
+	case *parse.FieldNode:
+		if len(node.Ident) > 1 && node.Ident[0] == "Params" {
+			return []string{node.Ident[1]}
+		}
+	case *parse.CommandNode:
+		if len(node.Args) >= 3 {
+			ident, isIdent := node.Args[0].(*parse.IdentifierNode)
+			field, isField := node.Args[1].(*parse.FieldNode)
+			key, isString := node.Args[2].(*parse.StringNode)
+			if isIdent && ident.Ident == "index" && isField && isString &&
+				len(field.Ident) == 1 && field.Ident[0] == "Params" {
+				return []string{key.Text}
+			}
+		}
+	}
+	return nil
+}
+
+func checkRouteConflicts(report *CheckReport, routeFiles map[string][]string) {
+	routes := make([]string, 0, len(routeFiles))
+	for route := range routeFiles {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	publicFiles := make(map[string]bool)
+	if files, err := PublicFiles(); err == nil {
+		for _, file := range files {
+			publicFiles["/"+file] = true
+		}
+	}
+
+	patterns := make(map[string]string)
+	for _, route := range routes {
+		files := routeFiles[route]
+		if len(files) > 1 {
+			report.add(files[1], 1, CheckError, "route %s is also defined by %s", route, strings.Join(files[:1], ", "))
+		}
+
+		pattern := paramRegex.ReplaceAllString(route, "[]")
+		if other, ok := patterns[pattern]; ok && other != route {
+			report.add(files[0], 1, CheckError, "route %s conflicts with %s", route, other)
+		} else {
+			patterns[pattern] = route
+		}
+
+		switch {
+		case route == "/api" || strings.HasPrefix(route, "/api/"):
+			report.add(files[0], 1, CheckError, "route %s is shadowed by API routing", route)
+		case publicFiles[route]:
+			report.add(files[0], 1, CheckError, "route %s is shadowed by %s", route, path.Join(AppConfig.PublicDir, route))
+		case staticMountFor(route) != "":
+			report.add(files[0], 1, CheckError, "route %s is shadowed by the static mount %s", route, staticMountFor(route))
+		case AppConfig.Sitemap && (route == "/sitemap.xml" || route == "/robots.txt"):
+			report.add(files[0], 1, CheckError, "route %s conflicts with the generated %s", route, route)
+		}
+	}
+}
+
+func nodeLine(tree *parse.Tree, n parse.Node) int {
+	location, _ := tree.ErrorContext(n)
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
+
+func walkTemplateNodes(n parse.Node, visit func(parse.Node)) {
+	if n == nil {
+		return
+	}
+
+	visit(n)
+
+	switch node := n.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			walkTemplateNodes(child, visit)
+		}
+	case *parse.ActionNode:
+		walkTemplateNodes(node.Pipe, visit)
+	case *parse.PipeNode:
+		if node == nil {
+			return
+		}
+		for _, cmd := range node.Cmds {
+			walkTemplateNodes(cmd, visit)
+		}
+	case *parse.CommandNode:
+		for _, arg := range node.Args {
+			walkTemplateNodes(arg, visit)
+		}
+	case *parse.ChainNode:
+		walkTemplateNodes(node.Node, visit)
+	case *parse.IfNode:
+		walkBranch(&node.BranchNode, visit)
+	case *parse.RangeNode:
+		walkBranch(&node.BranchNode, visit)
+	case *parse.WithNode:
+		walkBranch(&node.BranchNode, visit)
+	case *parse.TemplateNode:
+		walkTemplateNodes(node.Pipe, visit)
+	}
+}
+
+func walkBranch(node *parse.BranchNode, visit func(parse.Node)) {
+	walkTemplateNodes(node.Pipe, visit)
+	walkTemplateNodes(node.List, visit)
+	walkTemplateNodes(node.ElseList, visit)
+}
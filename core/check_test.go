@@ -0,0 +1,58 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckAppLines(t *testing.T) {
+	withTestSite(t, testSite(map[string]string{
+		"app/layout.html":         "<html>\n{{template \"content\" .}}\n</html>\n",
+		"app/components/nav.html": "<nav></nav>\n",
+		"app/index.html":          "{{define \"content\"}}\n<p>{{printf \"%s\" (asset \"x\")}}</p>\n{{end}}\n",
+		"app/funcs.html":          "{{define \"content\"}}\n<p>\n{{nosuchfunc .}}\n</p>\n{{end}}\n",
+		"app/missing.html":        "---\ntitle: x\n---\n{{define \"content\"}}\n{{template \"footer\"}}\n{{end}}\n",
+		"app/nocontent.html":      "<p>no content</p>\n",
+		"app/docs/guide.md":       "---\ntitle: Guide\nlayout: layouts/none.html\n---\n# Guide\n",
+		"app/blog/[id].html":      "{{define \"content\"}}{{.Params.id}}{{end}}\n",
+		"app/blog/[slug].html":    "{{define \"content\"}}{{.Params.slug}}{{end}}\n",
+	}))
+
+	report, err := CheckApp()
+	if err != nil {
+		t.Fatalf("CheckApp: %v", err)
+	}
+
+	for _, problem := range report.Problems {
+		if problem.File == "app/index.html" {
+			t.Errorf("unexpected problem with builtin and app funcs: %v", problem)
+		}
+	}
+
+	tests := []struct {
+		file    string
+		line    int
+		message string
+	}{
+		{"app/funcs.html", 3, `parse error: template: app/funcs.html:3: function "nosuchfunc" not defined`},
+		{"app/missing.html", 5, `template "footer" is not defined`},
+		{"app/nocontent.html", 1, `page does not define a "content" block`},
+		{"app/docs/guide.md", 3, "failed to read layout layouts/none.html"},
+		{"app/blog/[slug].html", 1, "route /blog/[slug] conflicts with /blog/[id]"},
+		{"app/components/nav.html", 1, `component "nav" is never used`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			for _, problem := range report.Problems {
+				if problem.File == tt.file && strings.HasPrefix(problem.Message, tt.message) {
+					if problem.Line != tt.line {
+						t.Errorf("line = %d, want %d", problem.Line, tt.line)
+					}
+					return
+				}
+			}
+			t.Errorf("no problem %q in %v", tt.message, report.Problems)
+		})
+	}
+}
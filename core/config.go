@@ -1,41 +1,226 @@
 package core
 
+import (
+	"io/fs"
+	"time"
+)
+
 type Config struct {
-	AppDir         string
-	StaticDir      string
-	Port           string
-	DevMode        bool
-	LiveReload     bool
-	DefaultCDNs    bool
-	TailwindCDN    string
-	JQueryCDN      string
-	LayoutPath     string
-	ComponentDir   string
-	AppName        string
-	Version        string
-	LogLevel       string
-	TemplateCache  bool
-	EnableCORS     bool
-	AllowedOrigins []string
-	RateLimit      int
+	AppDir                        string
+	StaticDir                     string
+	StaticMounts                  []StaticMount
+	PublicDir                     string
+	StaticListing                 bool
+	StaticBlockedPatterns         []string
+	StaticCacheControl            map[string]string
+	StaticDefaultCacheControl     string
+	ServePrecompressed            bool
+	Precompress                   bool
+	PrecompressMinSize            int
+	FS                            fs.FS
+	Port                          string
+	DevMode                       bool
+	LiveReload                    bool
+	DefaultCDNs                   bool
+	TailwindCDN                   string
+	TailwindLocal                 string
+	JQueryCDN                     string
+	JQueryIntegrity               string
+	JQueryLocal                   string
+	AnimateCSSCDN                 string
+	AnimateCSSIntegrity           string
+	AnimateCSSLocal               string
+	Assets                        []Asset
+	AssetFingerprinting           bool
+	Bundles                       []Bundle
+	ImageWidths                   []int
+	ImageQuality                  int
+	ImageQualities                []int
+	ImageCacheDir                 string
+	LayoutPath                    string
+	MarkdownLayout                string
+	MarkdownUnsafeHTML            bool
+	ComponentDir                  string
+	AppName                       string
+	BaseURL                       string
+	Sitemap                       bool
+	Robots                        []RobotsRule
+	Version                       string
+	LogLevel                      string
+	TemplateCache                 bool
+	TemplateCacheMode             string
+	TemplateCacheTTL              time.Duration
+	PartialBlockHeader            string
+	PartialBlockParam             string
+	PageCacheTTL                  time.Duration
+	PageCacheStaleWhileRevalidate time.Duration
+	PageCacheVaryHeaders          []string
+	PageCacheVaryCookies          []string
+	PageCacheMaxEntries           int
+	SessionSecret                 string
+	SessionCookieName             string
+	SessionCookieSecure           bool
+	SessionTTL                    time.Duration
+	SessionStore                  SessionStore
+	EnableCSRF                    bool
+	CSRFCookieName                string
+	CSRFCookieSecure              bool
+	CSRFHeader                    string
+	CSRFFieldName                 string
+	CSRFExempt                    []string
+	CSRFTrustedOrigins            []string
+	JWTSecret                     string
+	JWTKeyFiles                   []string
+	JWTIssuer                     string
+	JWTAudience                   []string
+	JWTClockSkew                  time.Duration
+	JWTAlgorithms                 []string
+	CredentialsFile               string
+	APIKeyHeader                  string
+	APIKeyQueryParam              string
+	BasicAuthRealm                string
+	OIDCIssuer                    string
+	OIDCClientID                  string
+	OIDCClientSecret              string
+	OIDCRedirectURL               string
+	OIDCScopes                    []string
+	OIDCLoginPath                 string
+	OIDCCallbackPath              string
+	OIDCLogoutPath                string
+	OIDCPostLogoutPath            string
+	OIDCRolesClaim                string
+	OIDCMockProvider              bool
+	AccessRules                   map[string]AccessRule
+	RolePermissions               map[string][]string
+	TrustedProxies                []string
+	EnableCORS                    bool
+	AllowedOrigins                []string
+	EnableRateLimit               bool
+	RateLimit                     int
+	RateLimitWindow               time.Duration
+	RateLimitBurst                int
+	RateLimitKey                  string
+	RateLimitRoutes               map[string]RateLimit
+	RateLimitStore                RateLimitStore
+	RateLimitMaxKeys              int
 }
 
 var AppConfig = Config{
-	AppDir:         "app",
-	StaticDir:      "static",
-	Port:           "3000",
-	DevMode:        true,
-	LiveReload:     true,
-	DefaultCDNs:    true,
-	TailwindCDN:    "https://cdn.tailwindcss.com",
-	JQueryCDN:      "https://code.jquery.com/jquery-3.7.1.min.js",
-	LayoutPath:     "app/layout.html",
-	ComponentDir:   "app/components",
-	AppName:        "Go on Airplanes",
-	Version:        "0.3.0",
-	LogLevel:       "info",
-	TemplateCache:  true,
-	EnableCORS:     false,
-	AllowedOrigins: []string{"*"},
-	RateLimit:      100,
+	AppDir:                "app",
+	StaticDir:             "static",
+	StaticMounts:          []StaticMount{},
+	PublicDir:             "public",
+	StaticListing:         false,
+	StaticBlockedPatterns: []string{"*.bak", "*.swp", "*.orig", "*~"},
+	StaticCacheControl: map[string]string{
+		".css":   "public, max-age=86400",
+		".js":    "public, max-age=86400",
+		".png":   "public, max-age=604800",
+		".jpg":   "public, max-age=604800",
+		".jpeg":  "public, max-age=604800",
+		".gif":   "public, max-age=604800",
+		".svg":   "public, max-age=604800",
+		".webp":  "public, max-age=604800",
+		".ico":   "public, max-age=604800",
+		".woff":  "public, max-age=2592000",
+		".woff2": "public, max-age=2592000",
+	},
+	StaticDefaultCacheControl:     "public, max-age=3600",
+	ServePrecompressed:            true,
+	Precompress:                   false,
+	PrecompressMinSize:            1024,
+	Port:                          "3000",
+	DevMode:                       true,
+	LiveReload:                    true,
+	DefaultCDNs:                   true,
+	TailwindCDN:                   "https://cdn.tailwindcss.com",
+	JQueryCDN:                     "https://code.jquery.com/jquery-3.7.1.min.js",
+	JQueryIntegrity:               "sha256-/JqT3SQfawRcv/BIHPThkBvs0OEvtFFmqPF/lYI/Cxo=",
+	AnimateCSSCDN:                 "https://cdnjs.cloudflare.com/ajax/libs/animate.css/4.1.1/animate.min.css",
+	AnimateCSSIntegrity:           "",
+	Assets:                        []Asset{},
+	AssetFingerprinting:           true,
+	Bundles:                       []Bundle{},
+	ImageWidths:                   []int{320, 640, 960, 1280, 1920},
+	ImageQuality:                  80,
+	ImageQualities:                []int{50, 65, 80, 90},
+	ImageCacheDir:                 ".cache/images",
+	LayoutPath:                    "app/layout.html",
+	MarkdownLayout:                "",
+	MarkdownUnsafeHTML:            false,
+	ComponentDir:                  "app/components",
+	AppName:                       "Go on Airplanes",
+	BaseURL:                       "",
+	Sitemap:                       true,
+	Robots:                        []RobotsRule{{UserAgent: "*", Allow: []string{"/"}, Disallow: []string{"/api/"}}},
+	Version:                       "0.3.0",
+	LogLevel:                      "info",
+	TemplateCache:                 true,
+	TemplateCacheMode:             "",
+	TemplateCacheTTL:              5 * time.Minute,
+	PartialBlockHeader:            "X-Partial-Block",
+	PartialBlockParam:             "_block",
+	PageCacheTTL:                  0,
+	PageCacheStaleWhileRevalidate: 0,
+	PageCacheVaryHeaders:          []string{},
+	PageCacheVaryCookies:          []string{},
+	PageCacheMaxEntries:           1000,
+	SessionSecret:                 "",
+	SessionCookieName:             "goa_session",
+	SessionCookieSecure:           false,
+	SessionTTL:                    24 * time.Hour,
+	SessionStore:                  nil,
+	EnableCSRF:                    false,
+	CSRFCookieName:                "goa_csrf",
+	CSRFCookieSecure:              false,
+	CSRFHeader:                    "X-CSRF-Token",
+	CSRFFieldName:                 "csrf_token",
+	CSRFExempt:                    []string{},
+	CSRFTrustedOrigins:            []string{},
+	JWTSecret:                     "",
+	JWTKeyFiles:                   []string{},
+	JWTIssuer:                     "",
+	JWTAudience:                   []string{},
+	JWTClockSkew:                  time.Minute,
+	JWTAlgorithms:                 []string{"HS256", "RS256", "ES256"},
+	CredentialsFile:               "credentials.json",
+	APIKeyHeader:                  "X-API-Key",
+	APIKeyQueryParam:              "",
+	BasicAuthRealm:                "Restricted",
+	OIDCIssuer:                    "",
+	OIDCClientID:                  "",
+	OIDCClientSecret:              "",
+	OIDCRedirectURL:               "",
+	OIDCScopes:                    []string{"openid", "email", "profile"},
+	OIDCLoginPath:                 "/auth/login",
+	OIDCCallbackPath:              "/auth/callback",
+	OIDCLogoutPath:                "/auth/logout",
+	OIDCPostLogoutPath:            "/",
+	OIDCRolesClaim:                "roles",
+	OIDCMockProvider:              false,
+	AccessRules:                   map[string]AccessRule{},
+	RolePermissions:               map[string][]string{},
+	TrustedProxies:                []string{},
+	EnableCORS:                    false,
+	AllowedOrigins:                []string{"*"},
+	EnableRateLimit:               false,
+	RateLimit:                     100,
+	RateLimitWindow:               time.Minute,
+	RateLimitBurst:                0,
+	RateLimitKey:                  "ip",
+	RateLimitRoutes:               map[string]RateLimit{},
+	RateLimitStore:                nil,
+	RateLimitMaxKeys:              10000,
+}
+
+func (c *Config) ResolvedTemplateCacheMode() string {
+	switch c.TemplateCacheMode {
+	case CacheModeDev, CacheModeProd, CacheModeTTL:
+		return c.TemplateCacheMode
+	}
+
+	if !c.TemplateCache {
+		return CacheModeDev
+	}
+	return CacheModeTTL
 }
@@ -0,0 +1,305 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	CredentialAPIKey = "api_key"
+	CredentialBasic  = "basic"
+)
+
+type credentialKey struct{}
+
+type Credential struct {
+	ID     string   `json:"id"`
+	Type   string   `json:"type"`
+	Hash   string   `json:"hash"`
+	Scopes []string `json:"scopes"`
+}
+
+type CredentialStore struct {
+	File        string
+	mutex       sync.RWMutex
+	credentials []Credential
+	apiKeys     map[string]*Credential
+	logger      *AppLogger
+}
+
+var (
+	defaultCredentials     *CredentialStore
+	defaultCredentialsErr  error
+	defaultCredentialsOnce sync.Once
+	defaultCredentialsUsed atomic.Bool
+)
+
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("goa-dummy-password"), bcrypt.DefaultCost)
+
+func NewCredentialStore(file string, logger *AppLogger) (*CredentialStore, error) {
+	store := &CredentialStore{File: file, logger: logger}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+
+	watchFile(file, store.Reload)
+	return store, nil
+}
+
+func (cs *CredentialStore) Reload() error {
+	data, err := os.ReadFile(cs.File)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file %s: %w", cs.File, err)
+	}
+
+	var file struct {
+		Credentials []Credential `json:"credentials"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse credentials file %s: %w", cs.File, err)
+	}
+
+	apiKeys := make(map[string]*Credential)
+	for i := range file.Credentials {
+		credential := &file.Credentials[i]
+		switch credential.Type {
+		case CredentialAPIKey:
+			digest, err := hex.DecodeString(strings.TrimPrefix(credential.Hash, "sha256:"))
+			if !strings.HasPrefix(credential.Hash, "sha256:") || err != nil || len(digest) != sha256.Size {
+				return fmt.Errorf("API key %q must use a sha256: hash", credential.ID)
+			}
+			apiKeys[string(digest)] = credential
+		case CredentialBasic:
+			if _, err := bcrypt.Cost([]byte(credential.Hash)); err != nil {
+				return fmt.Errorf("password for %q must use a bcrypt hash: %w", credential.ID, err)
+			}
+		default:
+			return fmt.Errorf("credential %q has unknown type %q", credential.ID, credential.Type)
+		}
+	}
+
+	cs.mutex.Lock()
+	cs.credentials = file.Credentials
+	cs.apiKeys = apiKeys
+	cs.mutex.Unlock()
+
+	if cs.logger != nil {
+		cs.logger.InfoLog.Printf("Loaded %d credentials from %s", len(file.Credentials), cs.File)
+	}
+	return nil
+}
+
+func (cs *CredentialStore) LookupAPIKey(key string) (*Credential, bool) {
+	digest := sha256.Sum256([]byte(key))
+
+	cs.mutex.RLock()
+	found, ok := cs.apiKeys[string(digest[:])]
+	cs.mutex.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	matched := *found
+	return &matched, true
+}
+
+func (cs *CredentialStore) LookupBasic(username, password string) (*Credential, bool) {
+	cs.mutex.RLock()
+	var found *Credential
+	for i := range cs.credentials {
+		credential := cs.credentials[i]
+		if credential.Type == CredentialBasic && subtle.ConstantTimeCompare([]byte(credential.ID), []byte(username)) == 1 {
+			found = &credential
+		}
+	}
+	cs.mutex.RUnlock()
+
+	if found == nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(found.Hash), []byte(password)) != nil {
+		return nil, false
+	}
+	return found, true
+}
+
+func (c *Credential) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == "*" || s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func HashCredential(secret string, password bool) (string, error) {
+	if password {
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	}
+
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func APIKeyMiddleware(store *CredentialStore, logger *AppLogger, scopes ...string) MiddlewareFunc {
+	var storeErr error
+	if store == nil {
+		store, storeErr = defaultCredentialStore(logger)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if storeErr != nil {
+				writeCredentialStoreError(w, r, logger, storeErr)
+				return
+			}
+
+			key := requestAPIKey(r)
+			if key == "" {
+				writeUnauthorized(w, r, `ApiKey realm="api"`, "Missing API key")
+				return
+			}
+
+			credential, ok := store.LookupAPIKey(key)
+			if !ok {
+				logger.WarnLog.Printf("Rejected API key for %s %s", r.Method, r.URL.Path)
+				writeUnauthorized(w, r, `ApiKey realm="api", error="invalid_key"`, "Invalid API key")
+				return
+			}
+
+			serveWithCredential(w, r, next, credential, scopes)
+		})
+	}
+}
+
+func BasicAuthMiddleware(store *CredentialStore, logger *AppLogger, scopes ...string) MiddlewareFunc {
+	var storeErr error
+	if store == nil {
+		store, storeErr = defaultCredentialStore(logger)
+	}
+
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, AppConfig.BasicAuthRealm)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if storeErr != nil {
+				writeCredentialStoreError(w, r, logger, storeErr)
+				return
+			}
+
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				writeUnauthorized(w, r, challenge, "Missing credentials")
+				return
+			}
+
+			credential, ok := store.LookupBasic(username, password)
+			if !ok {
+				logger.WarnLog.Printf("Rejected basic auth for user %q on %s %s", username, r.Method, r.URL.Path)
+				writeUnauthorized(w, r, challenge, "Invalid credentials")
+				return
+			}
+
+			serveWithCredential(w, r, next, credential, scopes)
+		})
+	}
+}
+
+func RequireScopes(scopes ...string) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, scope := range scopes {
+				if !HasScope(r, scope) {
+					writeForbidden(w, r, fmt.Sprintf("Missing scope %s", scope))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func HasScope(r *http.Request, scope string) bool {
+	if credential := GetCredential(r); credential != nil && credential.HasScope(scope) {
+		return true
+	}
+
+	claims := GetClaims(r)
+	if claims == nil {
+		return false
+	}
+	granted := strings.Fields(claims.String("scope"))
+	if scp, ok := claims["scp"].([]interface{}); ok {
+		for _, s := range scp {
+			if value, ok := s.(string); ok {
+				granted = append(granted, value)
+			}
+		}
+	}
+	return containsString(granted, scope)
+}
+
+func GetCredential(r *http.Request) *Credential {
+	credential, _ := r.Context().Value(credentialKey{}).(*Credential)
+	return credential
+}
+
+func serveWithCredential(w http.ResponseWriter, r *http.Request, next http.Handler, credential *Credential, scopes []string) {
+	for _, scope := range scopes {
+		if !credential.HasScope(scope) {
+			writeForbidden(w, r, fmt.Sprintf("Missing scope %s", scope))
+			return
+		}
+	}
+
+	next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), credentialKey{}, credential)))
+}
+
+func writeForbidden(w http.ResponseWriter, r *http.Request, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api") {
+		RenderError(w, message, http.StatusForbidden)
+		return
+	}
+	http.Error(w, "Forbidden", http.StatusForbidden)
+}
+
+func writeCredentialStoreError(w http.ResponseWriter, r *http.Request, logger *AppLogger, err error) {
+	logger.ErrorLog.Printf("Cannot authenticate %s %s: %v", r.Method, r.URL.Path, err)
+	if strings.HasPrefix(r.URL.Path, "/api") {
+		RenderError(w, "Authentication is unavailable", http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+}
+
+func defaultCredentialStore(logger *AppLogger) (*CredentialStore, error) {
+	defaultCredentialsUsed.Store(true)
+	defaultCredentialsOnce.Do(func() {
+		defaultCredentials, defaultCredentialsErr = NewCredentialStore(AppConfig.CredentialsFile, logger)
+	})
+	return defaultCredentials, defaultCredentialsErr
+}
+
+func requestAPIKey(r *http.Request) string {
+	key := r.Header.Get(AppConfig.APIKeyHeader)
+	if key == "" && AppConfig.APIKeyQueryParam != "" {
+		key = r.URL.Query().Get(AppConfig.APIKeyQueryParam)
+	}
+	return key
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeCredentialsFile(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatalf("write credentials: %v", err)
+	}
+	return file
+}
+
+func withDefaultCredentials(t *testing.T, file string) {
+	t.Helper()
+	withTestConfig(t)
+	AppConfig.CredentialsFile = file
+
+	reset := func() {
+		defaultCredentials, defaultCredentialsErr = nil, nil
+		defaultCredentialsOnce = sync.Once{}
+		defaultCredentialsUsed.Store(false)
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestCredentialStoreHashes(t *testing.T) {
+	apiKey, _ := HashCredential("secret-key", false)
+	password, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	tests := []struct {
+		name  string
+		typ   string
+		hash  string
+		valid bool
+	}{
+		{"api key sha256", CredentialAPIKey, apiKey, true},
+		{"api key bcrypt", CredentialAPIKey, string(password), false},
+		{"basic bcrypt", CredentialBasic, string(password), true},
+		{"basic sha256", CredentialBasic, apiKey, false},
+		{"basic plain", CredentialBasic, "secret", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeCredentialsFile(t, `{"credentials": [{"id": "acme", "type": "`+tt.typ+`", "hash": "`+tt.hash+`"}]}`)
+			_, err := NewCredentialStore(file, nil)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestCredentialMiddlewareWithBrokenStore(t *testing.T) {
+	withDefaultCredentials(t, filepath.Join(t.TempDir(), "missing.json"))
+
+	tests := []struct {
+		name       string
+		middleware MiddlewareFunc
+	}{
+		{"api key", APIKeyMiddleware(nil, testLogger())},
+		{"basic", BasicAuthMiddleware(nil, testLogger())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler ran without credentials")
+			}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orders", nil))
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+			}
+		})
+	}
+}
+
+func TestInitRejectsBrokenCredentials(t *testing.T) {
+	withDefaultCredentials(t, writeCredentialsFile(t, `{"credentials": [{"id": "preview", "type": "basic", "hash": "sha256:00"}]}`))
+	withTestSite(t, testSite(nil))
+	AppConfig.EnableRateLimit = false
+	AppConfig.EnableCSRF = false
+	AppConfig.LiveReload = false
+
+	app := &GonAirApp{Router: NewRouter(testLogger()), Config: &AppConfig, Logger: testLogger()}
+	app.Router.Use(BasicAuthMiddleware(nil, app.Logger))
+	if err := app.Init(); err == nil || !strings.Contains(err.Error(), "bcrypt") {
+		t.Errorf("Init error = %v, want a bcrypt error for the sha256 password hash", err)
+	}
+}
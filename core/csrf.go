@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	csrfTokenLength = 32
+	csrfSessionKey  = "_csrf"
+)
+
+var userTemplateFuncs = []string{"csrfToken", "csrfField", "can"}
+
+type csrfKey struct{}
+
+type csrfState struct {
+	w      http.ResponseWriter
+	req    *http.Request
+	logger *AppLogger
+	once   sync.Once
+	secret []byte
+}
+
+var csrfSessionOrderWarning sync.Once
+
+var csrfSafeMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+func CSRFMiddleware(logger *AppLogger) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &csrfState{w: w, logger: logger}
+			r = r.WithContext(context.WithValue(r.Context(), csrfKey{}, state))
+			state.req = r
+
+			if csrfSafeMethods[r.Method] || isCSRFExempt(r.URL.Path) || hasHeaderCredentials(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := checkCSRF(r, state); err != nil {
+				logger.WarnLog.Printf("CSRF check failed for %s %s: %v", r.Method, r.URL.Path, err)
+				if strings.HasPrefix(r.URL.Path, "/api") {
+					RenderError(w, "Invalid CSRF token", http.StatusForbidden)
+				} else {
+					http.Error(w, "Forbidden - invalid CSRF token", http.StatusForbidden)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func CSRFToken(r *http.Request) string {
+	state, ok := r.Context().Value(csrfKey{}).(*csrfState)
+	if !ok {
+		if GetSession(r) == nil {
+			return ""
+		}
+		state = &csrfState{req: r}
+	}
+	return maskCSRFToken(state.token())
+}
+
+func checkCSRF(r *http.Request, state *csrfState) error {
+	if origin := requestOrigin(r); origin != "" && !isTrustedOrigin(r, origin) {
+		return fmt.Errorf("origin %s is not allowed", origin)
+	}
+
+	secret := state.existing()
+	if secret == nil {
+		return fmt.Errorf("no CSRF cookie or session token")
+	}
+
+	token := r.Header.Get(AppConfig.CSRFHeader)
+	if token == "" {
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			r.ParseMultipartForm(32 << 20)
+		}
+		token = r.PostFormValue(AppConfig.CSRFFieldName)
+	}
+	if token == "" {
+		return fmt.Errorf("missing CSRF token")
+	}
+
+	if subtle.ConstantTimeCompare(unmaskCSRFToken(token), secret) != 1 {
+		return fmt.Errorf("CSRF token does not match")
+	}
+	return nil
+}
+
+func (st *csrfState) existing() []byte {
+	if session := GetSession(st.req); session != nil {
+		secret, _ := base64.RawURLEncoding.DecodeString(session.GetString(csrfSessionKey))
+		if len(secret) == csrfTokenLength {
+			return secret
+		}
+		return nil
+	}
+
+	cookie, err := st.req.Cookie(AppConfig.CSRFCookieName)
+	if err != nil {
+		return nil
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(secret) != csrfTokenLength {
+		return nil
+	}
+	return secret
+}
+
+func (st *csrfState) token() []byte {
+	st.once.Do(func() {
+		if st.secret = st.existing(); st.secret != nil {
+			return
+		}
+
+		st.secret = make([]byte, csrfTokenLength)
+		if _, err := io.ReadFull(rand.Reader, st.secret); err != nil {
+			panic(fmt.Sprintf("failed to generate CSRF token: %v", err))
+		}
+		encoded := base64.RawURLEncoding.EncodeToString(st.secret)
+
+		if session := GetSession(st.req); session != nil {
+			session.Set(csrfSessionKey, encoded)
+			return
+		}
+
+		http.SetCookie(st.w, &http.Cookie{
+			Name:     AppConfig.CSRFCookieName,
+			Value:    encoded,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   AppConfig.CSRFCookieSecure,
+			SameSite: http.SameSiteLaxMode,
+		})
+	})
+	return st.secret
+}
+
+func maskCSRFToken(secret []byte) string {
+	pad := make([]byte, len(secret))
+	if _, err := io.ReadFull(rand.Reader, pad); err != nil {
+		panic(fmt.Sprintf("failed to generate CSRF token: %v", err))
+	}
+
+	masked := make([]byte, 0, 2*len(secret))
+	masked = append(masked, pad...)
+	for i := range secret {
+		masked = append(masked, pad[i]^secret[i])
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+func unmaskCSRFToken(token string) []byte {
+	masked, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(masked) != 2*csrfTokenLength {
+		return nil
+	}
+
+	secret := make([]byte, csrfTokenLength)
+	for i := range secret {
+		secret[i] = masked[i] ^ masked[csrfTokenLength+i]
+	}
+	return secret
+}
+
+func isCSRFExempt(requestPath string) bool {
+	requestPath = normalizePath(requestPath)
+	if mockOIDCEnabled() && strings.HasPrefix(requestPath, mockOIDCPath+"/") {
+		return true
+	}
+	for _, pattern := range AppConfig.CSRFExempt {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(requestPath, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+		if matched, _ := path.Match(normalizePath(pattern), requestPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHeaderCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	return AppConfig.APIKeyHeader != "" && r.Header.Get(AppConfig.APIKeyHeader) != ""
+}
+
+func requestOrigin(r *http.Request) string {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		return origin
+	}
+
+	referer, err := url.Parse(r.Header.Get("Referer"))
+	if err != nil || referer.Host == "" {
+		return ""
+	}
+	return referer.Scheme + "://" + referer.Host
+}
+
+func isTrustedOrigin(r *http.Request, origin string) bool {
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, RequestHost(r)) {
+		return true
+	}
+
+	for _, trusted := range AppConfig.CSRFTrustedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(trusted, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func templateCSRFToken(ctx *RouteContext) string {
+	if ctx == nil || ctx.Request == nil || ctx.Request.req == nil {
+		return ""
+	}
+
+	req := ctx.Request.req
+	if state, ok := req.Context().Value(csrfKey{}).(*csrfState); ok && GetSession(state.req) == nil && GetSession(req) != nil {
+		csrfSessionOrderWarning.Do(func() {
+			state.logger.WarnLog.Printf("SessionMiddleware runs after CSRFMiddleware: CSRF tokens use a cookie instead of the session")
+		})
+	}
+	return CSRFToken(req)
+}
+
+func templateCSRFField(ctx *RouteContext) template.HTML {
+	token := templateCSRFToken(ctx)
+	if token == "" {
+		return ""
+	}
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(AppConfig.CSRFFieldName), token))
+}
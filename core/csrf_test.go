@@ -0,0 +1,203 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRFTokenMasking(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x5a}, csrfTokenLength)
+
+	first, second := maskCSRFToken(secret), maskCSRFToken(secret)
+	if first == second {
+		t.Error("masking the same secret twice gave the same token")
+	}
+
+	flipped := []byte(first)
+	if flipped[0] == 'A' {
+		flipped[0] = 'B'
+	} else {
+		flipped[0] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		match bool
+	}{
+		{"masked", first, true},
+		{"masked again", second, true},
+		{"flipped byte", string(flipped), false},
+		{"half length", first[:43], false},
+		{"too long", first + "AAAA", false},
+		{"not base64", strings.Repeat("!", 86), false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bytes.Equal(unmaskCSRFToken(tt.token), secret); got != tt.match {
+				t.Errorf("unmasked matches secret = %v, want %v", got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCSRFOrigin(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.CSRFTrustedOrigins = []string{"https://admin.example.com/"}
+
+	tests := []struct {
+		name    string
+		origin  string
+		referer string
+		want    string
+		trusted bool
+	}{
+		{"no origin", "", "", "", false},
+		{"same host", "https://example.com", "", "https://example.com", true},
+		{"same host any case", "https://EXAMPLE.com", "", "https://EXAMPLE.com", true},
+		{"other host", "https://evil.com", "", "https://evil.com", false},
+		{"subdomain", "https://evil.example.com", "", "https://evil.example.com", false},
+		{"trusted origin", "https://admin.example.com", "", "https://admin.example.com", true},
+		{"trusted host other scheme", "http://admin.example.com", "", "http://admin.example.com", false},
+		{"null origin", "null", "", "null", false},
+		{"referer fallback", "", "https://example.com/form?x=1", "https://example.com", true},
+		{"cross-site referer", "", "https://evil.com/page", "https://evil.com", false},
+		{"origin wins over referer", "https://evil.com", "https://example.com/form", "https://evil.com", false},
+		{"relative referer", "", "/form", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://example.com/form", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+
+			origin := requestOrigin(req)
+			if origin != tt.want {
+				t.Fatalf("requestOrigin = %q, want %q", origin, tt.want)
+			}
+			if origin != "" {
+				if got := isTrustedOrigin(req, origin); got != tt.trusted {
+					t.Errorf("isTrustedOrigin = %v, want %v", got, tt.trusted)
+				}
+			}
+		})
+	}
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.CSRFCookieName = "goa_csrf"
+	AppConfig.CSRFHeader = "X-CSRF-Token"
+	AppConfig.CSRFFieldName = "csrf_token"
+	AppConfig.CSRFExempt = []string{"/webhooks/*"}
+	AppConfig.CSRFTrustedOrigins = nil
+	AppConfig.APIKeyHeader = "X-API-Key"
+
+	handler := CSRFMiddleware(testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "goa_csrf" || !cookies[0].HttpOnly {
+		t.Fatalf("cookies after GET = %+v, want one HttpOnly goa_csrf cookie", cookies)
+	}
+	cookie, token := cookies[0], w.Body.String()
+
+	tests := []struct {
+		name   string
+		path   string
+		cookie bool
+		header string
+		form   string
+		origin string
+		auth   map[string]string
+		status int
+	}{
+		{"header token", "/form", true, token, "", "", nil, http.StatusOK},
+		{"form token", "/form", true, "", token, "", nil, http.StatusOK},
+		{"fresh mask", "/form", true, maskCSRFToken(unmaskCSRFToken(token)), "", "", nil, http.StatusOK},
+		{"same origin", "/form", true, token, "", "https://example.com", nil, http.StatusOK},
+		{"missing token", "/form", true, "", "", "", nil, http.StatusForbidden},
+		{"missing cookie", "/form", false, token, "", "", nil, http.StatusForbidden},
+		{"wrong token", "/form", true, maskCSRFToken(bytes.Repeat([]byte{1}, csrfTokenLength)), "", "", nil, http.StatusForbidden},
+		{"cross origin", "/form", true, token, "", "https://evil.com", nil, http.StatusForbidden},
+		{"api error", "/api/items", true, "", "", "", nil, http.StatusForbidden},
+		{"exempt", "/webhooks/stripe", false, "", "", "https://stripe.com", nil, http.StatusOK},
+		{"bearer token", "/api/items", false, "", "", "https://evil.com", map[string]string{"Authorization": "Bearer eyJhbGciOi"}, http.StatusOK},
+		{"basic auth", "/api/items", false, "", "", "", map[string]string{"Authorization": "Basic YWNtZTpzZWNyZXQ="}, http.StatusOK},
+		{"api key", "/api/items", false, "", "", "", map[string]string{"X-API-Key": "secret-key"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body *strings.Reader
+			if tt.form != "" {
+				body = strings.NewReader(url.Values{"csrf_token": {tt.form}}.Encode())
+			} else {
+				body = strings.NewReader("")
+			}
+			req := httptest.NewRequest(http.MethodPost, "https://example.com"+tt.path, body)
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie {
+				req.AddCookie(cookie)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-CSRF-Token", tt.header)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			for name, value := range tt.auth {
+				req.Header.Set(name, value)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareSession(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.SessionSecret = "0123456789abcdef0123456789abcdef"
+	AppConfig.SessionCookieName = "goa_session"
+	AppConfig.CSRFCookieName = "goa_csrf"
+	AppConfig.CSRFHeader = "X-CSRF-Token"
+
+	logger := testLogger()
+	handler := SessionMiddleware(NewMemorySessionStore(), logger)(CSRFMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	})))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "goa_session" {
+		t.Fatalf("cookies after GET = %+v, want only the session cookie", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/form", nil)
+	req.AddCookie(cookies[0])
+	req.Header.Set("X-CSRF-Token", w.Body.String())
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("POST with session token status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
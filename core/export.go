@@ -0,0 +1,422 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var linkAttrRegex = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*["']([^"']+)["']`)
+
+type ParamsProvider func() ([]map[string]string, error)
+
+type ExportReport struct {
+	OutDir        string
+	Pages         []string
+	StaticFiles   int
+	Precompressed int
+	Skipped       []string
+	BrokenLinks   []BrokenLink
+}
+
+type BrokenLink struct {
+	Page string
+	Link string
+}
+
+func (r *Router) RegisterParamsProvider(route string, provider ParamsProvider) {
+	if r.ParamsProviders == nil {
+		r.ParamsProviders = make(map[string]ParamsProvider)
+	}
+	r.ParamsProviders[route] = provider
+}
+
+func (r *Router) expandRoute(route Route) ([]string, error) {
+	if !route.IsParam {
+		return []string{route.Path}, nil
+	}
+
+	provider, ok := r.ParamsProviders[route.Path]
+	if !ok {
+		return nil, nil
+	}
+
+	paramSets, err := provider()
+	if err != nil {
+		return nil, fmt.Errorf("params provider for %s failed: %w", route.Path, err)
+	}
+
+	paths := make([]string, 0, len(paramSets))
+	for _, params := range paramSets {
+		var missing, invalid []string
+		expanded := paramRegex.ReplaceAllStringFunc(route.Path, func(segment string) string {
+			name := segment[1 : len(segment)-1]
+			value, ok := params[name]
+			if !ok || value == "" {
+				missing = append(missing, name)
+				return segment
+			}
+			if value == "." || strings.Contains(value, "..") || strings.ContainsAny(value, "/\\") {
+				invalid = append(invalid, value)
+				return segment
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("params provider for %s returned a set without %v", route.Path, missing)
+		}
+		if len(invalid) > 0 {
+			return nil, fmt.Errorf("params provider for %s returned invalid values %q: values must not contain / or ..", route.Path, invalid)
+		}
+		paths = append(paths, expanded)
+	}
+
+	return paths, nil
+}
+
+func (app *GonAirApp) Export(outDir string) (*ExportReport, error) {
+	startTime := time.Now()
+	app.Logger.InfoLog.Printf("Exporting static site to %s...", outDir)
+
+	if err := checkExportDir(outDir); err != nil {
+		return nil, err
+	}
+	if err := os.RemoveAll(outDir); err != nil {
+		return nil, fmt.Errorf("failed to clean output directory: %w", err)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	report := &ExportReport{OutDir: outDir}
+	exported := make(map[string]bool)
+	rendered := make(map[string][]byte)
+
+	for _, route := range app.Router.Routes {
+		if !app.Router.isPageRoute(route) || isErrorPageRoute(route.Path) {
+			continue
+		}
+
+		paths, err := app.Router.expandRoute(route)
+		if err != nil {
+			return nil, err
+		}
+		if paths == nil {
+			app.Logger.WarnLog.Printf("Skipping %s: no params provider registered", route.Path)
+			report.Skipped = append(report.Skipped, route.Path)
+			continue
+		}
+
+		for _, pagePath := range paths {
+			body, status := app.renderForExport(pagePath)
+			if status != http.StatusOK {
+				return nil, fmt.Errorf("rendering %s returned status %d", pagePath, status)
+			}
+
+			if err := writeExportFile(outDir, exportFilePath(pagePath), body); err != nil {
+				return nil, err
+			}
+
+			exported[normalizePath(pagePath)] = true
+			rendered[pagePath] = body
+			report.Pages = append(report.Pages, pagePath)
+		}
+	}
+
+	staticCount, err := app.exportStatic(outDir, exported)
+	if err != nil {
+		return nil, err
+	}
+	report.StaticFiles = staticCount
+
+	publicCount, err := app.exportPublic(outDir, exported)
+	if err != nil {
+		return nil, err
+	}
+	report.StaticFiles += publicCount
+
+	if err := app.exportSEOFiles(outDir, exported); err != nil {
+		return nil, err
+	}
+
+	for _, status := range []int{http.StatusNotFound, http.StatusInternalServerError} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		app.Router.serveErrorPage(rec, req, status)
+
+		if err := writeExportFile(outDir, fmt.Sprintf("%d.html", status), rec.Body.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if AppConfig.Precompress {
+		count, err := PrecompressDir(outDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to precompress export: %w", err)
+		}
+		report.Precompressed = count
+	}
+
+	sort.Strings(report.Pages)
+	for _, pagePath := range report.Pages {
+		for _, link := range findInternalLinks(pagePath, rendered[pagePath]) {
+			if !exported[link] {
+				report.BrokenLinks = append(report.BrokenLinks, BrokenLink{Page: pagePath, Link: link})
+			}
+		}
+	}
+
+	if len(report.BrokenLinks) > 0 {
+		for _, broken := range report.BrokenLinks {
+			app.Logger.ErrorLog.Printf("Broken link on %s: %s", broken.Page, broken.Link)
+		}
+		return report, fmt.Errorf("export found %d broken internal links", len(report.BrokenLinks))
+	}
+
+	app.Logger.InfoLog.Printf("Exported %d pages and %d static files in %v",
+		len(report.Pages), report.StaticFiles, time.Since(startTime).Round(time.Millisecond))
+
+	return report, nil
+}
+
+func (app *GonAirApp) renderForExport(pagePath string) ([]byte, int) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, (&url.URL{Path: pagePath}).EscapedPath(), nil)
+	app.Router.ServeHTTP(rec, req)
+	return rec.Body.Bytes(), rec.Code
+}
+
+func (app *GonAirApp) exportStatic(outDir string, exported map[string]bool) (int, error) {
+	manifest := app.Router.Marley.AssetManifest()
+
+	count := 0
+	for i, mount := range app.Router.StaticMounts() {
+		prefix := staticMountPrefix(mount.Prefix)
+		staticFS, err := AppConfig.SubFS(mount.Dir)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open static directory %s: %w", mount.Dir, err)
+		}
+
+		err = fs.WalkDir(staticFS, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || isBlockedStaticPath(p) {
+				return nil
+			}
+
+			content, err := fs.ReadFile(staticFS, p)
+			if err != nil {
+				return fmt.Errorf("failed to read static file %s: %w", p, err)
+			}
+
+			if err := writeExportFile(outDir, path.Join(prefix, p), content); err != nil {
+				return err
+			}
+
+			exported[prefix+p] = true
+			count++
+
+			if hashed, ok := manifest.files[p]; ok && i == 0 && AppConfig.AssetFingerprinting {
+				if err := writeExportFile(outDir, path.Join(prefix, hashed), content); err != nil {
+					return err
+				}
+				exported[prefix+hashed] = true
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, err
+		}
+	}
+
+	for name, bundle := range app.Router.Marley.Bundles() {
+		files := map[string][]byte{name: bundle.Content}
+		if hashed, ok := manifest.files[name]; ok && AppConfig.AssetFingerprinting {
+			files[hashed] = bundle.Content
+		}
+		if bundle.SourceMap != nil {
+			files[name+".map"] = bundle.SourceMap
+		}
+
+		for p, content := range files {
+			if err := writeExportFile(outDir, path.Join("static", p), content); err != nil {
+				return 0, err
+			}
+			exported["/static/"+p] = true
+		}
+		count++
+	}
+
+	if AppConfig.AssetFingerprinting {
+		manifestJSON, err := manifest.JSON()
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode asset manifest: %w", err)
+		}
+		if err := writeExportFile(outDir, "static/asset-manifest.json", manifestJSON); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func (app *GonAirApp) exportPublic(outDir string, exported map[string]bool) (int, error) {
+	files, err := PublicFiles()
+	if err != nil {
+		return 0, err
+	}
+
+	publicFS, err := AppConfig.SubFS(AppConfig.PublicDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open public directory: %w", err)
+	}
+
+	count := 0
+	for _, name := range files {
+		if exported["/"+name] {
+			app.Logger.WarnLog.Printf("Skipping %s: it conflicts with an exported page", path.Join(AppConfig.PublicDir, name))
+			continue
+		}
+
+		content, err := fs.ReadFile(publicFS, name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read public file %s: %w", name, err)
+		}
+		if err := writeExportFile(outDir, name, content); err != nil {
+			return 0, err
+		}
+		exported["/"+name] = true
+		count++
+	}
+
+	return count, nil
+}
+
+func (app *GonAirApp) exportSEOFiles(outDir string, exported map[string]bool) error {
+	if !AppConfig.Sitemap {
+		return nil
+	}
+
+	if AppConfig.BaseURL == "" {
+		app.Logger.WarnLog.Printf("Skipping sitemap.xml and robots.txt: BaseURL is not configured")
+		return nil
+	}
+
+	files, err := app.Router.SitemapFiles(AppConfig.BaseURL)
+	if err != nil {
+		return err
+	}
+
+	files["robots.txt"] = app.Router.RobotsTxt(AppConfig.BaseURL)
+
+	for name, content := range files {
+		if exported["/"+name] {
+			app.Logger.InfoLog.Printf("Keeping %s from %s", name, AppConfig.PublicDir)
+			continue
+		}
+		if err := writeExportFile(outDir, name, content); err != nil {
+			return err
+		}
+		exported["/"+name] = true
+	}
+
+	return nil
+}
+
+func checkExportDir(outDir string) error {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return fmt.Errorf("invalid output directory: %w", err)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	protected := []string{AppConfig.AppDir, AppConfig.StaticDir, AppConfig.PublicDir, AppConfig.ImageCacheDir}
+	for _, mount := range AppConfig.StaticMounts {
+		protected = append(protected, mount.Dir)
+	}
+
+	for _, dir := range append([]string{cwd}, protected...) {
+		if dir == "" {
+			continue
+		}
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if absOut == absDir || strings.HasPrefix(absDir, absOut+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to export into %s: it contains %s", outDir, dir)
+		}
+		if dir != cwd && strings.HasPrefix(absOut, absDir+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to export into %s: it is inside %s", outDir, dir)
+		}
+	}
+
+	return nil
+}
+
+func exportFilePath(pagePath string) string {
+	pagePath = strings.Trim(normalizePath(pagePath), "/")
+	if pagePath == "" {
+		return "index.html"
+	}
+	return path.Join(pagePath, "index.html")
+}
+
+func writeExportFile(outDir, name string, content []byte) error {
+	target := filepath.Join(outDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", name, err)
+	}
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func isErrorPageRoute(route string) bool {
+	return route == "/404" || route == "/500" || route == "/error"
+}
+
+func findInternalLinks(pagePath string, body []byte) []string {
+	base := &url.URL{Path: pagePath}
+	if !strings.HasSuffix(pagePath, "/") {
+		base.Path += "/"
+	}
+
+	seen := make(map[string]bool)
+	var links []string
+	for _, match := range linkAttrRegex.FindAllSubmatch(body, -1) {
+		raw := strings.TrimSpace(string(match[1]))
+		if raw == "" || strings.HasPrefix(raw, "#") || strings.HasPrefix(raw, "//") || strings.Contains(raw, "{{") {
+			continue
+		}
+
+		ref, err := url.Parse(raw)
+		if err != nil || ref.Scheme != "" || ref.Host != "" {
+			continue
+		}
+
+		resolved := normalizePath(base.ResolveReference(ref).Path)
+		if !seen[resolved] {
+			seen[resolved] = true
+			links = append(links, resolved)
+		}
+	}
+
+	return links
+}
@@ -0,0 +1,81 @@
+package core
+
+import "testing"
+
+func TestCheckExportDir(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.AppDir = "app"
+	AppConfig.StaticDir = "static"
+	AppConfig.PublicDir = "public"
+	AppConfig.ImageCacheDir = ".cache/images"
+	AppConfig.StaticMounts = []StaticMount{{Prefix: "/assets", Dir: "assets"}}
+
+	tests := []struct {
+		outDir string
+		valid  bool
+	}{
+		{"dist", true},
+		{"build/site", true},
+		{".", false},
+		{"..", false},
+		{"app", false},
+		{"app/out", false},
+		{"static", false},
+		{"public", false},
+		{"public/out", false},
+		{"assets", false},
+		{".cache", false},
+		{".cache/images", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.outDir, func(t *testing.T) {
+			err := checkExportDir(tt.outDir)
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestExpandRoute(t *testing.T) {
+	router := NewRouter(testLogger())
+	route := Route{Path: "/blog/[slug]", IsParam: true}
+
+	tests := []struct {
+		slug  string
+		path  string
+		file  string
+		valid bool
+	}{
+		{"hello", "/blog/hello", "blog/hello/index.html", true},
+		{"café", "/blog/café", "blog/café/index.html", true},
+		{"a b", "/blog/a b", "blog/a b/index.html", true},
+		{"a/b", "", "", false},
+		{`a\b`, "", "", false},
+		{"..", "", "", false},
+		{".", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			router.ParamsProviders[route.Path] = func() ([]map[string]string, error) {
+				return []map[string]string{{"slug": tt.slug}}, nil
+			}
+			paths, err := router.expandRoute(route)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+			if !tt.valid {
+				return
+			}
+			if len(paths) != 1 || paths[0] != tt.path {
+				t.Fatalf("paths = %q, want [%q]", paths, tt.path)
+			}
+			if file := exportFilePath(paths[0]); file != tt.file {
+				t.Errorf("file = %q, want %q", file, tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+type AssetManifest struct {
+	files   map[string]string
+	reverse map[string]string
+}
+
+func NewAssetManifest() *AssetManifest {
+	return &AssetManifest{
+		files:   make(map[string]string),
+		reverse: make(map[string]string),
+	}
+}
+
+func BuildAssetManifest(staticFS fs.FS) (*AssetManifest, error) {
+	manifest := NewAssetManifest()
+
+	err := fs.WalkDir(staticFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := fs.ReadFile(staticFS, p)
+		if err != nil {
+			return fmt.Errorf("failed to read static file %s: %w", p, err)
+		}
+
+		manifest.add(p, content)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func (am *AssetManifest) add(name string, content []byte) {
+	if old, ok := am.files[name]; ok {
+		delete(am.reverse, old)
+	}
+
+	hashed := fingerprintPath(name, content)
+	am.files[name] = hashed
+	am.reverse[hashed] = name
+}
+
+func fingerprintPath(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:10]
+
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+func (am *AssetManifest) URL(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if hashed, ok := am.files[name]; ok && AppConfig.AssetFingerprinting {
+		return "/static/" + hashed
+	}
+	return "/static/" + name
+}
+
+func (am *AssetManifest) Resolve(hashed string) (string, bool) {
+	original, ok := am.reverse[hashed]
+	return original, ok
+}
+
+func (am *AssetManifest) Files() map[string]string {
+	files := make(map[string]string, len(am.files))
+	for name, hashed := range am.files {
+		files[name] = hashed
+	}
+	return files
+}
+
+func (am *AssetManifest) JSON() ([]byte, error) {
+	return json.MarshalIndent(am.files, "", "  ")
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(filepath.FromSlash(name))
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(filepath.FromSlash(name))
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(filepath.FromSlash(name))
+}
+
+func (c *Config) UsesEmbeddedFS() bool {
+	return c.FS != nil && !c.DevMode
+}
+
+func (c *Config) SiteFS() fs.FS {
+	if c.UsesEmbeddedFS() {
+		return c.FS
+	}
+	return osFS{}
+}
+
+func (c *Config) SubFS(dir string) (fs.FS, error) {
+	if !c.UsesEmbeddedFS() {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(c.FS, fsPath(dir))
+}
+
+func fsPath(p string) string {
+	return path.Clean(filepath.ToSlash(p))
+}
+
+func fsPathHasPrefix(p, dir string) bool {
+	p, dir = fsPath(p), fsPath(dir)
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"io"
+	"log"
+	"testing"
+	"testing/fstest"
+)
+
+func testLogger() *AppLogger {
+	return &AppLogger{
+		InfoLog:  log.New(io.Discard, "", 0),
+		ErrorLog: log.New(io.Discard, "", 0),
+		WarnLog:  log.New(io.Discard, "", 0),
+	}
+}
+
+func withTestConfig(t *testing.T) {
+	t.Helper()
+	saved := AppConfig
+	t.Cleanup(func() { AppConfig = saved })
+}
+
+func testSite(files map[string]string) fstest.MapFS {
+	site := fstest.MapFS{
+		"app/layout.html": {Data: []byte(`{{template "content" .}}`)},
+		"app/index.html":  {Data: []byte(`{{define "content"}}home{{end}}`)},
+	}
+	for name, content := range files {
+		site[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return site
+}
+
+func withTestSite(t *testing.T, site fstest.MapFS) {
+	t.Helper()
+	withTestConfig(t)
+	AppConfig.DevMode = false
+	AppConfig.FS = site
+	AppConfig.PublicDir = ""
+	AppConfig.MarkdownLayout = ""
+	AppConfig.StaticMounts = nil
+}
+
+func newTestRouter(t *testing.T) *Router {
+	t.Helper()
+	router := NewRouter(testLogger())
+	if err := router.InitRoutes(); err != nil {
+		t.Fatalf("InitRoutes: %v", err)
+	}
+	return router
+}
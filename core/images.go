@@ -0,0 +1,407 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	imageVariants singleflight.Group
+	imageEncoders = make(chan struct{}, runtime.GOMAXPROCS(0))
+)
+
+type imageOptions struct {
+	Width   int
+	Quality int
+	Format  string
+}
+
+type responsiveImages struct {
+	manifest *AssetManifest
+	staticFS fs.FS
+	mutex    sync.Mutex
+	configs  map[string]image.Config
+}
+
+func isResizableImage(name string) bool {
+	return imageFormatFromExt(path.Ext(name)) != ""
+}
+
+func imageFormatFromExt(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".png":
+		return "png"
+	case ".jpg", ".jpeg":
+		return "jpeg"
+	case ".gif":
+		return "gif"
+	}
+	return ""
+}
+
+func isImageRequest(req *http.Request, name string) bool {
+	query := req.URL.Query()
+	return isResizableImage(name) && (query.Has("w") || query.Has("q") || query.Has("fm"))
+}
+
+func parseImageOptions(query url.Values, name string) (imageOptions, error) {
+	opts := imageOptions{
+		Quality: AppConfig.ImageQuality,
+		Format:  imageFormatFromExt(path.Ext(name)),
+	}
+
+	if w := query.Get("w"); w != "" {
+		width, err := strconv.Atoi(w)
+		if err != nil || !isAllowedImageWidth(width) {
+			return opts, fmt.Errorf("width %q is not allowed", w)
+		}
+		opts.Width = width
+	}
+
+	if q := query.Get("q"); q != "" {
+		quality, err := strconv.Atoi(q)
+		if err != nil || !isAllowedImageQuality(quality) {
+			return opts, fmt.Errorf("quality %q is not allowed", q)
+		}
+		opts.Quality = quality
+	}
+
+	if fm := query.Get("fm"); fm != "" {
+		format := imageFormatFromExt("." + fm)
+		if format == "" {
+			return opts, fmt.Errorf("format %q is not supported", fm)
+		}
+		opts.Format = format
+	}
+
+	return opts, nil
+}
+
+func isAllowedImageWidth(width int) bool {
+	for _, allowed := range AppConfig.ImageWidths {
+		if width == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedImageQuality(quality int) bool {
+	if quality == AppConfig.ImageQuality {
+		return true
+	}
+	for _, allowed := range AppConfig.ImageQualities {
+		if quality == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (o imageOptions) contentType() string {
+	return "image/" + o.Format
+}
+
+func (o imageOptions) variantName(hashedName string) string {
+	base := strings.TrimSuffix(hashedName, path.Ext(hashedName))
+	name := fmt.Sprintf("%s.w%d", base, o.Width)
+	if o.Format == "jpeg" {
+		name += fmt.Sprintf(".q%d", o.Quality)
+	}
+
+	ext := "." + o.Format
+	if o.Format == "jpeg" {
+		ext = ".jpg"
+	}
+	return name + ext
+}
+
+func (r *Router) serveImage(w http.ResponseWriter, req *http.Request, staticFS fs.FS, name string) {
+	opts, err := parseImageOptions(req.URL.Query(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content, err := fs.ReadFile(staticFS, name)
+	if err != nil {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	variant, err := r.imageVariant(name, content, opts)
+	if err != nil {
+		r.Logger.ErrorLog.Printf("Failed to resize image %s: %v", name, err)
+		http.Error(w, "Failed to process image", http.StatusInternalServerError)
+		return
+	}
+
+	setStaticCacheControl(w, name)
+	w.Header().Set("Content-Type", opts.contentType())
+
+	var modTime time.Time
+	if info, err := fs.Stat(staticFS, name); err == nil {
+		modTime = info.ModTime()
+	}
+	http.ServeContent(w, req, "", modTime, bytes.NewReader(variant))
+}
+
+func (r *Router) imageVariant(name string, content []byte, opts imageOptions) ([]byte, error) {
+	key := opts.variantName(fingerprintPath(name, content))
+	cachePath := ""
+	if AppConfig.ImageCacheDir != "" {
+		cachePath = filepath.Join(AppConfig.ImageCacheDir, filepath.FromSlash(key))
+		if cached, err := os.ReadFile(cachePath); err == nil {
+			return cached, nil
+		}
+	}
+
+	variant, err, _ := imageVariants.Do(key, func() (interface{}, error) {
+		if cachePath != "" {
+			if cached, err := os.ReadFile(cachePath); err == nil {
+				return cached, nil
+			}
+		}
+
+		imageEncoders <- struct{}{}
+		variant, err := encodeImageVariant(content, opts)
+		<-imageEncoders
+		if err != nil {
+			return nil, err
+		}
+
+		if cachePath != "" {
+			if err := writeImageCache(cachePath, variant); err != nil {
+				r.Logger.WarnLog.Printf("Failed to cache image variant %s: %v", cachePath, err)
+			}
+		}
+		return variant, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variant.([]byte), nil
+}
+
+func encodeImageVariant(content []byte, opts imageOptions) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == "gif" && opts.Format == "gif" {
+		if animation, err := gif.DecodeAll(bytes.NewReader(content)); err == nil && len(animation.Image) > 1 {
+			return content, nil
+		}
+	}
+
+	dst := src
+	if opts.Width > 0 && opts.Width < src.Bounds().Dx() {
+		dst = resizeImage(src, opts.Width)
+	}
+
+	var buf bytes.Buffer
+	switch opts.Format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, flattenImage(dst), &jpeg.Options{Quality: opts.Quality})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	default:
+		err = fmt.Errorf("unsupported format %q", opts.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func resizeImage(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	height := (srcH*width + srcW/2) / srcW
+	if height < 1 {
+		height = 1
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := (y + 1) * srcH / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := (x + 1) * srcW / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[offset])
+					g += uint32(rgba.Pix[offset+1])
+					b += uint32(rgba.Pix[offset+2])
+					a += uint32(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+func flattenImage(src image.Image) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Over)
+	return dst
+}
+
+func writeImageCache(cachePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cachePath), ".variant-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), cachePath)
+}
+
+func newResponsiveImages(manifest *AssetManifest) *responsiveImages {
+	staticFS, _ := AppConfig.SubFS(AppConfig.StaticDir)
+	return &responsiveImages{
+		manifest: manifest,
+		staticFS: staticFS,
+		configs:  make(map[string]image.Config),
+	}
+}
+
+func (ri *responsiveImages) config(name string) (image.Config, error) {
+	ri.mutex.Lock()
+	defer ri.mutex.Unlock()
+
+	if cfg, ok := ri.configs[name]; ok {
+		return cfg, nil
+	}
+	if ri.staticFS == nil {
+		return image.Config{}, errors.New("static directory is not available")
+	}
+
+	file, err := ri.staticFS.Open(name)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer file.Close()
+
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	ri.configs[name] = cfg
+	return cfg, nil
+}
+
+func (ri *responsiveImages) render(name string, attrs ...string) (template.HTML, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !isResizableImage(name) {
+		return "", fmt.Errorf("img: %s is not a PNG, JPEG or GIF image", name)
+	}
+	if len(attrs)%2 != 0 {
+		return "", fmt.Errorf("img: attributes for %s must be name/value pairs", name)
+	}
+
+	cfg, err := ri.config(name)
+	if err != nil {
+		return "", fmt.Errorf("img: failed to read %s: %w", name, err)
+	}
+
+	src := ri.manifest.URL(name)
+
+	var srcset []string
+	for _, width := range AppConfig.ImageWidths {
+		if width < cfg.Width {
+			srcset = append(srcset, fmt.Sprintf("%s?w=%d %dw", src, width, width))
+		}
+	}
+	srcset = append(srcset, fmt.Sprintf("%s %dw", src, cfg.Width))
+
+	values := map[string]string{
+		"alt":   "",
+		"sizes": "100vw",
+	}
+	order := []string{"alt", "sizes"}
+	for i := 0; i < len(attrs); i += 2 {
+		key := strings.ToLower(attrs[i])
+		if key == "src" || key == "srcset" {
+			return "", fmt.Errorf("img: %s attribute is generated and cannot be set", key)
+		}
+		if _, ok := values[key]; !ok {
+			order = append(order, key)
+		}
+		values[key] = attrs[i+1]
+	}
+
+	var tag strings.Builder
+	fmt.Fprintf(&tag, `<img src="%s" srcset="%s" width="%d" height="%d"`,
+		html.EscapeString(src), html.EscapeString(strings.Join(srcset, ", ")), cfg.Width, cfg.Height)
+	for _, key := range order {
+		fmt.Fprintf(&tag, ` %s="%s"`, html.EscapeString(key), html.EscapeString(values[key]))
+	}
+	tag.WriteString(">")
+
+	return template.HTML(tag.String()), nil
+}
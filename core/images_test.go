@@ -0,0 +1,76 @@
+package core
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestParseImageOptionsQuality(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.ImageQuality = 80
+	AppConfig.ImageQualities = []int{50, 90}
+
+	tests := []struct {
+		q       string
+		quality int
+		valid   bool
+	}{
+		{"", 80, true},
+		{"80", 80, true},
+		{"50", 50, true},
+		{"90", 90, true},
+		{"51", 0, false},
+		{"100", 0, false},
+		{"0", 0, false},
+		{"high", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.q, func(t *testing.T) {
+			query := url.Values{}
+			if tt.q != "" {
+				query.Set("q", tt.q)
+			}
+			opts, err := parseImageOptions(query, "img/hero.jpg")
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+			if tt.valid && opts.Quality != tt.quality {
+				t.Errorf("quality = %d, want %d", opts.Quality, tt.quality)
+			}
+		})
+	}
+}
+
+func TestImageVariantConcurrent(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.ImageCacheDir = t.TempDir()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	router := NewRouter(testLogger())
+
+	var wg sync.WaitGroup
+	for _, width := range []int{16, 16, 16, 32, 32, 32} {
+		wg.Add(1)
+		go func(width int) {
+			defer wg.Done()
+			variant, err := router.imageVariant("img/test.png", buf.Bytes(), imageOptions{Width: width, Format: "png"})
+			if err != nil {
+				t.Errorf("imageVariant(%d): %v", width, err)
+				return
+			}
+			config, err := png.DecodeConfig(bytes.NewReader(variant))
+			if err != nil || config.Width != width {
+				t.Errorf("variant for width %d decoded as %+v (err: %v)", width, config, err)
+			}
+		}(width)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,519 @@
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenMalformed = errors.New("token is malformed")
+	ErrTokenSignature = errors.New("token signature is invalid")
+	ErrTokenExpired   = errors.New("token has expired")
+	ErrTokenNotYet    = errors.New("token is not valid yet")
+	ErrTokenIssuer    = errors.New("token issuer is not accepted")
+	ErrTokenAudience  = errors.New("token audience is not accepted")
+)
+
+var jwtAlgorithms = []string{"HS256", "RS256", "ES256"}
+
+type claimsKey struct{}
+
+type Claims map[string]interface{}
+
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	Key       interface{}
+}
+
+type JWTKeySet struct {
+	Keys []JWTKey
+}
+
+type JWTOptions struct {
+	Issuer     string
+	Audience   []string
+	ClockSkew  time.Duration
+	Algorithms []string
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+}
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	K         string `json:"k"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+func (c Claims) String(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		var audience []string
+		for _, value := range aud {
+			if s, ok := value.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+		return audience
+	}
+	return nil
+}
+
+func (c Claims) Time(key string) time.Time {
+	switch value := c[key].(type) {
+	case float64:
+		return time.Unix(int64(value), 0)
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return time.Unix(n, 0)
+		}
+	case int64:
+		return time.Unix(value, 0)
+	case int:
+		return time.Unix(int64(value), 0)
+	}
+	return time.Time{}
+}
+
+func JWTMiddleware(keys *JWTKeySet, logger *AppLogger) MiddlewareFunc {
+	if keys == nil {
+		var err error
+		if keys, err = LoadJWTKeys(); err != nil {
+			logger.ErrorLog.Printf("Failed to load JWT keys, every bearer token will be rejected: %v", err)
+			keys = &JWTKeySet{}
+		}
+	}
+	if len(keys.Keys) == 0 {
+		logger.WarnLog.Printf("No JWT keys configured: every bearer token will be rejected")
+	}
+
+	options := JWTOptions{
+		Issuer:     AppConfig.JWTIssuer,
+		Audience:   AppConfig.JWTAudience,
+		ClockSkew:  AppConfig.JWTClockSkew,
+		Algorithms: AppConfig.JWTAlgorithms,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				writeUnauthorized(w, r, `Bearer realm="api"`, "Missing bearer token")
+				return
+			}
+
+			claims, err := VerifyJWT(token, keys, options)
+			if err != nil {
+				logger.WarnLog.Printf("Rejected bearer token for %s %s: %v", r.Method, r.URL.Path, err)
+				writeUnauthorized(w, r, fmt.Sprintf(`Bearer realm="api", error="invalid_token", error_description=%q`, err.Error()), "Invalid bearer token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+func GetClaims(r *http.Request) Claims {
+	claims, _ := r.Context().Value(claimsKey{}).(Claims)
+	return claims
+}
+
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, challenge, message string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	if strings.HasPrefix(r.URL.Path, "/api") {
+		RenderError(w, message, http.StatusUnauthorized)
+		return
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+func VerifyJWT(token string, keys *JWTKeySet, options JWTOptions) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	algorithms := options.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = jwtAlgorithms
+	}
+	if !containsString(algorithms, header.Algorithm) {
+		return nil, fmt.Errorf("algorithm %q is not accepted", header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	verified := false
+	for _, key := range keys.candidates(header) {
+		if verifyJWTSignature(key, parts[0]+"."+parts[1], signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrTokenSignature
+	}
+
+	var claims Claims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	now := time.Now()
+	if exp := claims.Time("exp"); !exp.IsZero() && now.After(exp.Add(options.ClockSkew)) {
+		return nil, ErrTokenExpired
+	}
+	if nbf := claims.Time("nbf"); !nbf.IsZero() && now.Add(options.ClockSkew).Before(nbf) {
+		return nil, ErrTokenNotYet
+	}
+	if options.Issuer != "" && claims.Issuer() != options.Issuer {
+		return nil, ErrTokenIssuer
+	}
+	if len(options.Audience) > 0 {
+		accepted := false
+		for _, aud := range claims.Audience() {
+			if containsString(options.Audience, aud) {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return nil, ErrTokenAudience
+		}
+	}
+
+	return claims, nil
+}
+
+func (ks *JWTKeySet) candidates(header jwtHeader) []JWTKey {
+	var matching, byID []JWTKey
+	for _, key := range ks.Keys {
+		if key.Algorithm != header.Algorithm {
+			continue
+		}
+		matching = append(matching, key)
+		if header.KeyID != "" && key.ID == header.KeyID {
+			byID = append(byID, key)
+		}
+	}
+	if len(byID) > 0 {
+		return byID
+	}
+	return matching
+}
+
+func SignJWT(claims Claims, key JWTKey) (string, error) {
+	header, err := json.Marshal(jwtHeader{Algorithm: key.Algorithm, KeyID: key.ID, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, k, digest[:]); err == nil {
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	default:
+		return "", fmt.Errorf("key %q cannot sign tokens", key.ID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func verifyJWTSignature(key JWTKey, input string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(input))
+
+	switch k := key.Key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+	case *rsa.PrivateKey:
+		return rsa.VerifyPKCS1v15(&k.PublicKey, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		return verifyES256(k, digest[:], signature)
+	case *ecdsa.PrivateKey:
+		return verifyES256(&k.PublicKey, digest[:], signature)
+	}
+	return false
+}
+
+func verifyES256(key *ecdsa.PublicKey, digest, signature []byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(key, digest, r, s)
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func validateJWTConfig() error {
+	for _, algorithm := range AppConfig.JWTAlgorithms {
+		if !containsString(jwtAlgorithms, algorithm) {
+			return fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+		}
+	}
+	if _, err := LoadJWTKeys(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func LoadJWTKeys() (*JWTKeySet, error) {
+	keys := &JWTKeySet{}
+	if AppConfig.JWTSecret != "" {
+		keys.Keys = append(keys.Keys, JWTKey{Algorithm: "HS256", Key: []byte(AppConfig.JWTSecret)})
+	}
+
+	for _, file := range AppConfig.JWTKeyFiles {
+		loaded, err := LoadJWTKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		keys.Keys = append(keys.Keys, loaded.Keys...)
+	}
+
+	return keys, nil
+}
+
+func LoadJWTKeyFile(file string) (*JWTKeySet, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", file, err)
+	}
+
+	if filepath.Ext(file) == ".json" {
+		keys, err := ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key set %s: %w", file, err)
+		}
+		return keys, nil
+	}
+
+	keys, err := parsePEMKeys(data, strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", file, err)
+	}
+	return keys, nil
+}
+
+func ParseJWKS(data []byte) (*JWTKeySet, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := &JWTKeySet{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.parse()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.KeyID, err)
+		}
+		if key.Algorithm == "" {
+			continue
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) parse() (JWTKey, error) {
+	key := JWTKey{ID: jwk.KeyID}
+
+	switch jwk.KeyType {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil {
+			return key, err
+		}
+		key.Algorithm, key.Key = "HS256", secret
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return key, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return key, err
+		}
+		key.Algorithm = "RS256"
+		key.Key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if jwk.Curve != "P-256" {
+			return key, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return key, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return key, err
+		}
+		key.Algorithm = "ES256"
+		key.Key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	}
+
+	if jwk.Algorithm != "" && key.Algorithm != "" && jwk.Algorithm != key.Algorithm {
+		key.Algorithm = ""
+	}
+	return key, nil
+}
+
+func parsePEMKeys(data []byte, id string) (*JWTKeySet, error) {
+	keys := &JWTKeySet{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var (
+			parsed interface{}
+			err    error
+		)
+		switch block.Type {
+		case "PUBLIC KEY":
+			parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				parsed = cert.PublicKey
+			}
+		case "PRIVATE KEY":
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			parsed, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		key := JWTKey{ID: id, Key: parsed}
+		switch k := parsed.(type) {
+		case *rsa.PublicKey, *rsa.PrivateKey:
+			key.Algorithm = "RS256"
+		case *ecdsa.PublicKey:
+			if k.Curve == elliptic.P256() {
+				key.Algorithm = "ES256"
+			}
+		case *ecdsa.PrivateKey:
+			if k.Curve == elliptic.P256() {
+				key.Algorithm = "ES256"
+			}
+		}
+		if key.Algorithm == "" {
+			return nil, fmt.Errorf("unsupported key type %T", parsed)
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("no PEM keys found")
+	}
+	return keys, nil
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyJWT(t *testing.T) {
+	secret := JWTKey{Algorithm: "HS256", Key: []byte("test-secret")}
+	other := JWTKey{Algorithm: "HS256", Key: []byte("other-secret")}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	rsaSigner := JWTKey{ID: "rsa", Algorithm: "RS256", Key: rsaKey}
+	ecSigner := JWTKey{ID: "ec", Algorithm: "ES256", Key: ecKey}
+
+	keys := &JWTKeySet{Keys: []JWTKey{
+		secret,
+		{ID: "rsa", Algorithm: "RS256", Key: &rsaKey.PublicKey},
+		{ID: "ec", Algorithm: "ES256", Key: &ecKey.PublicKey},
+	}}
+	now := time.Now().Unix()
+
+	sign := func(claims Claims, key JWTKey) string {
+		token, err := SignJWT(claims, key)
+		if err != nil {
+			t.Fatalf("SignJWT: %v", err)
+		}
+		return token
+	}
+	unsigned := func(header string) string {
+		encode := base64.RawURLEncoding.EncodeToString
+		return encode([]byte(header)) + "." + encode([]byte(`{"sub":"alice"}`)) + "."
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		options JWTOptions
+		err     error
+	}{
+		{"HS256", sign(Claims{"sub": "alice", "exp": now + 60}, secret), JWTOptions{}, nil},
+		{"RS256", sign(Claims{"sub": "alice"}, rsaSigner), JWTOptions{}, nil},
+		{"ES256", sign(Claims{"sub": "alice"}, ecSigner), JWTOptions{}, nil},
+		{"malformed", "not-a-token", JWTOptions{}, ErrTokenMalformed},
+		{"bad signature", sign(Claims{"sub": "alice"}, other), JWTOptions{}, ErrTokenSignature},
+		{"tampered payload", tamper(sign(Claims{"sub": "alice"}, secret)), JWTOptions{}, ErrTokenSignature},
+		{"alg none", unsigned(`{"alg":"none","typ":"JWT"}`), JWTOptions{}, errAlgorithm},
+		{"algorithm not accepted", sign(Claims{"sub": "alice"}, secret), JWTOptions{Algorithms: []string{"RS256"}}, errAlgorithm},
+		{"expired", sign(Claims{"exp": now - 120}, secret), JWTOptions{ClockSkew: time.Minute}, ErrTokenExpired},
+		{"expired within skew", sign(Claims{"exp": now - 30}, secret), JWTOptions{ClockSkew: time.Minute}, nil},
+		{"not yet valid", sign(Claims{"nbf": now + 120}, secret), JWTOptions{ClockSkew: time.Minute}, ErrTokenNotYet},
+		{"issuer", sign(Claims{"iss": "https://id.example.com"}, secret), JWTOptions{Issuer: "https://id.example.com"}, nil},
+		{"wrong issuer", sign(Claims{"iss": "https://evil.example.com"}, secret), JWTOptions{Issuer: "https://id.example.com"}, ErrTokenIssuer},
+		{"audience list", sign(Claims{"aud": []string{"web", "api"}}, secret), JWTOptions{Audience: []string{"api"}}, nil},
+		{"wrong audience", sign(Claims{"aud": "web"}, secret), JWTOptions{Audience: []string{"api"}}, ErrTokenAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyJWT(tt.token, keys, tt.options)
+			switch {
+			case tt.err == nil && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err == errAlgorithm:
+				if err == nil || !strings.Contains(err.Error(), "is not accepted") {
+					t.Errorf("err = %v, want algorithm rejection", err)
+				}
+			case tt.err != nil && !errors.Is(err, tt.err):
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+var errAlgorithm = errors.New("algorithm rejected")
+
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`))
+	return strings.Join(parts, ".")
+}
+
+func TestValidateJWTConfig(t *testing.T) {
+	dir := t.TempDir()
+	badKey := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(badKey, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		keyFiles   []string
+		algorithms []string
+		valid      bool
+	}{
+		{"defaults", nil, nil, true},
+		{"supported algorithms", nil, []string{"HS256", "ES256"}, true},
+		{"unsupported algorithm", nil, []string{"none"}, false},
+		{"missing key file", []string{filepath.Join(dir, "missing.pem")}, nil, false},
+		{"unparseable key file", []string{badKey}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t)
+			AppConfig.JWTKeyFiles = tt.keyFiles
+			AppConfig.JWTAlgorithms = tt.algorithms
+
+			if err := validateJWTConfig(); (err == nil) != tt.valid {
+				t.Errorf("validateJWTConfig() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestJWTMiddlewareBadKeysRejectTokens(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.JWTSecret = "test-secret"
+	AppConfig.JWTKeyFiles = []string{filepath.Join(t.TempDir(), "missing.pem")}
+
+	token, err := SignJWT(Claims{"sub": "alice"}, JWTKey{Algorithm: "HS256", Key: []byte("test-secret")})
+	if err != nil {
+		t.Fatalf("SignJWT: %v", err)
+	}
+
+	handler := JWTMiddleware(nil, testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler ran with unloadable JWT keys")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
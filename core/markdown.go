@@ -0,0 +1,277 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+const markdownContentTemplate = `{{define "head"}}{{with markdownPage}}{{with .FrontMatter.description}}<meta name="description" content="{{.}}">{{end}}{{end}}{{end}}` +
+	`{{define "content"}}<article class="markdown-body">{{with markdownPage}}{{if .ShowTOC}}<nav class="markdown-toc">{{.TOC}}</nav>{{end}}{{.HTML}}{{end}}</article>{{end}}`
+
+type MarkdownHeading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+type MarkdownPage struct {
+	Title       string
+	FrontMatter map[string]string
+	Headings    []MarkdownHeading
+	HTML        template.HTML
+	TOC         template.HTML
+	ShowTOC     bool
+}
+
+var (
+	markdownRenderer       = newMarkdownRenderer()
+	unsafeMarkdownRenderer = newMarkdownRenderer(goldmarkhtml.WithUnsafe())
+)
+
+func newMarkdownRenderer(options ...renderer.Option) goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(options...),
+	)
+}
+
+func RenderMarkdown(source []byte) (*MarkdownPage, error) {
+	frontMatter, body, err := parseFrontMatter(source)
+	if err != nil {
+		return nil, err
+	}
+	if layout := frontMatter["layout"]; layout != "" && !validMarkdownLayout(layout) {
+		return nil, fmt.Errorf("invalid layout %q in front matter: must be a relative path inside %s", layout, AppConfig.AppDir)
+	}
+
+	md := markdownRenderer
+	if AppConfig.MarkdownUnsafeHTML {
+		md = unsafeMarkdownRenderer
+	}
+
+	doc := md.Parser().Parse(text.NewReader(body))
+
+	var headings []MarkdownHeading
+	err = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		id := ""
+		if value, ok := heading.AttributeString("id"); ok {
+			if b, ok := value.([]byte); ok {
+				id = string(b)
+			}
+		}
+
+		headings = append(headings, MarkdownHeading{
+			Level: heading.Level,
+			ID:    id,
+			Text:  nodeText(heading, body),
+		})
+
+		if id != "" {
+			anchor := ast.NewLink()
+			anchor.Destination = []byte("#" + id)
+			anchor.SetAttributeString("class", []byte("heading-anchor"))
+			anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+			heading.AppendChild(heading, anchor)
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to process markdown headings: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := md.Renderer().Render(&buf, body, doc); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
+	}
+
+	page := &MarkdownPage{
+		Title:       frontMatter["title"],
+		FrontMatter: frontMatter,
+		Headings:    headings,
+		HTML:        template.HTML(buf.String()),
+		TOC:         buildTOC(headings),
+		ShowTOC:     len(headings) > 1 && frontMatter["toc"] != "false",
+	}
+
+	if page.Title == "" {
+		for _, heading := range headings {
+			if heading.Level == 1 {
+				page.Title = heading.Text
+				break
+			}
+		}
+	}
+
+	return page, nil
+}
+
+func validMarkdownLayout(layout string) bool {
+	return fs.ValidPath(layout) && !strings.Contains(layout, "\\")
+}
+
+func markdownLayoutFile(layout string) string {
+	return fsPath(filepath.Join(AppConfig.AppDir, layout))
+}
+
+func markdownLayoutFiles(siteFS fs.FS, paths []string) map[string]bool {
+	layouts := make(map[string]bool)
+	for _, p := range paths {
+		if filepath.Ext(p) != ".md" {
+			continue
+		}
+		source, err := fs.ReadFile(siteFS, p)
+		if err != nil {
+			continue
+		}
+		frontMatter, _, err := parseFrontMatter(source)
+		if err != nil {
+			continue
+		}
+		if layout := frontMatter["layout"]; layout != "" && validMarkdownLayout(layout) {
+			layouts[markdownLayoutFile(layout)] = true
+		}
+	}
+	return layouts
+}
+
+func parseFrontMatter(source []byte) (map[string]string, []byte, error) {
+	frontMatter := make(map[string]string)
+
+	normalized := bytes.ReplaceAll(source, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(normalized, []byte("---\n")) {
+		return frontMatter, normalized, nil
+	}
+
+	rest := normalized[len("---\n"):]
+	end := bytes.Index(rest, []byte("\n---"))
+	if end == -1 {
+		return nil, nil, fmt.Errorf("unterminated front matter")
+	}
+
+	block := rest[:end]
+	body := rest[end+len("\n---"):]
+	if i := bytes.IndexByte(body, '\n'); i != -1 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+
+	for i, line := range strings.Split(string(block), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return nil, nil, fmt.Errorf("invalid front matter on line %d: %q", i+2, line)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' && value[len(value)-1] == '"' || value[0] == '\'' && value[len(value)-1] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+
+		frontMatter[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+
+	return frontMatter, body, nil
+}
+
+func splitPageFrontMatter(content []byte) (map[string]string, []byte, error) {
+	if !bytes.HasPrefix(content, []byte("---\n")) && !bytes.HasPrefix(content, []byte("---\r\n")) {
+		return nil, content, nil
+	}
+
+	frontMatter, body, err := parseFrontMatter(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	consumedLines := bytes.Count(content, []byte("\n")) - bytes.Count(body, []byte("\n"))
+	padded := append(bytes.Repeat([]byte("\n"), consumedLines), body...)
+
+	return frontMatter, padded, nil
+}
+
+func nodeText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch t := c.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				sb.WriteByte(' ')
+			}
+		case *ast.String:
+			sb.Write(t.Value)
+		default:
+			sb.WriteString(nodeText(c, source))
+		}
+	}
+	return sb.String()
+}
+
+func buildTOC(headings []MarkdownHeading) template.HTML {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	minLevel := headings[0].Level
+	for _, heading := range headings {
+		if heading.Level < minLevel {
+			minLevel = heading.Level
+		}
+	}
+
+	var sb strings.Builder
+	depth := 0
+	for _, heading := range headings {
+		if heading.ID == "" {
+			continue
+		}
+
+		level := heading.Level - minLevel + 1
+		opened := false
+		for depth < level {
+			sb.WriteString("<ul>")
+			depth++
+			opened = true
+		}
+		for depth > level {
+			sb.WriteString("</li></ul>")
+			depth--
+		}
+		if !opened {
+			sb.WriteString("</li>")
+		}
+
+		fmt.Fprintf(&sb, `<li><a href="#%s">%s</a>`, html.EscapeString(heading.ID), html.EscapeString(heading.Text))
+	}
+
+	for ; depth > 0; depth-- {
+		sb.WriteString("</li></ul>")
+	}
+
+	return template.HTML(sb.String())
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdownRawHTML(t *testing.T) {
+	withTestConfig(t)
+	source := []byte("# Title\n\n<script>alert(1)</script>\n")
+
+	tests := []struct {
+		name   string
+		unsafe bool
+		want   bool
+	}{
+		{"omitted by default", false, false},
+		{"kept when enabled", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig.MarkdownUnsafeHTML = tt.unsafe
+			page, err := RenderMarkdown(source)
+			if err != nil {
+				t.Fatalf("RenderMarkdown: %v", err)
+			}
+			if got := strings.Contains(string(page.HTML), "<script>"); got != tt.want {
+				t.Errorf("raw HTML kept = %v, want %v: %s", got, tt.want, page.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownLayout(t *testing.T) {
+	tests := []struct {
+		layout string
+		valid  bool
+	}{
+		{"layouts/docs.html", true},
+		{"docs.html", true},
+		{"/etc/passwd", false},
+		{"../secrets/layout.html", false},
+		{"layouts/../../layout.html", false},
+		{`layouts\..\..\layout.html`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			_, err := RenderMarkdown([]byte("---\nlayout: " + tt.layout + "\n---\n# Page\n"))
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestMarkdownLayoutIsNotAPage(t *testing.T) {
+	withTestSite(t, testSite(map[string]string{
+		"app/layouts/docs.html": `<main class="docs">{{template "content" .}}</main>`,
+		"app/docs/guide.md":     "---\ntitle: Guide\nlayout: layouts/docs.html\n---\n# Guide\n",
+	}))
+	router := newTestRouter(t)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/docs/guide", http.StatusOK, `<main class="docs">`},
+		{"/layouts/docs", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body is missing %q:\n%s", tt.body, w.Body.String())
+			}
+		})
+	}
+
+	report, err := CheckApp()
+	if err != nil {
+		t.Fatalf("CheckApp: %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("check reported problems for a front matter layout: %v", report.Problems)
+	}
+}
@@ -1,56 +1,216 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"text/template/parse"
 	"time"
 )
 
+const (
+	CacheModeDev  = "dev"
+	CacheModeProd = "prod"
+	CacheModeTTL  = "ttl"
+)
+
 type Marley struct {
 	Templates       map[string]*template.Template
 	Components      map[string]*template.Template
 	LayoutTemplate  *template.Template
+	MarkdownPages   map[string]*MarkdownPage
+	PageMeta        map[string]map[string]string
+	PageFiles       map[string]string
+	assetManifest   *AssetManifest
+	bundles         map[string]*BuiltBundle
 	ComponentsCache map[string]string
 	mutex           sync.RWMutex
 	cacheExpiry     time.Time
 	cacheTTL        time.Duration
+	forceReload     bool
+	fileModTimes    map[string]time.Time
+	stats           TemplateCacheStats
 	Logger          *AppLogger
 }
 
+type TemplateCacheStats struct {
+	Mode             string        `json:"mode"`
+	Hits             uint64        `json:"hits"`
+	Misses           uint64        `json:"misses"`
+	Reloads          uint64        `json:"reloads"`
+	ForcedReloads    uint64        `json:"forced_reloads"`
+	Templates        int           `json:"templates"`
+	TrackedFiles     int           `json:"tracked_files"`
+	LastLoad         time.Time     `json:"last_load"`
+	LastLoadDuration time.Duration `json:"last_load_duration"`
+	Expiry           time.Time     `json:"expiry,omitempty"`
+}
+
 func NewMarley(logger *AppLogger) *Marley {
+	ttl := AppConfig.TemplateCacheTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
 	return &Marley{
 		Templates:       make(map[string]*template.Template),
 		Components:      make(map[string]*template.Template),
+		MarkdownPages:   make(map[string]*MarkdownPage),
+		PageMeta:        make(map[string]map[string]string),
+		PageFiles:       make(map[string]string),
+		assetManifest:   NewAssetManifest(),
+		bundles:         make(map[string]*BuiltBundle),
 		ComponentsCache: make(map[string]string),
-		cacheTTL:        5 * time.Minute,
+		cacheTTL:        ttl,
+		fileModTimes:    make(map[string]time.Time),
 		Logger:          logger,
 	}
 }
 
+func (m *Marley) CacheMode() string {
+	return AppConfig.ResolvedTemplateCacheMode()
+}
+
 func (m *Marley) LoadTemplates() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
-	now := time.Now()
-	if !m.cacheExpiry.IsZero() && now.Before(m.cacheExpiry) && AppConfig.TemplateCache {
+	if !m.needsReload(time.Now()) {
+		m.stats.Hits++
+		return nil
+	}
+	m.stats.Misses++
+
+	return m.loadTemplates()
+}
+
+func (m *Marley) ReloadTemplates() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.CacheMode() == CacheModeProd && !m.stats.LastLoad.IsZero() {
+		m.Logger.WarnLog.Printf("Template reload skipped: cache mode is %q", CacheModeProd)
 		return nil
 	}
 
+	m.stats.ForcedReloads++
+	return m.loadTemplates()
+}
+
+func (m *Marley) needsReload(now time.Time) bool {
+	if m.stats.LastLoad.IsZero() {
+		return true
+	}
+
+	switch m.CacheMode() {
+	case CacheModeProd:
+		return false
+	case CacheModeTTL:
+		if m.forceReload {
+			return true
+		}
+		if now.Before(m.cacheExpiry) {
+			return false
+		}
+		if m.sourcesChanged() {
+			return true
+		}
+		m.cacheExpiry = now.Add(m.cacheTTL)
+		m.stats.Expiry = m.cacheExpiry
+		return false
+	default:
+		return true
+	}
+}
+
+func (m *Marley) sourcesChanged() bool {
+	current, err := collectTemplateModTimes()
+	if err != nil {
+		m.Logger.WarnLog.Printf("Failed to check template modification times: %v", err)
+		return true
+	}
+
+	if len(current) != len(m.fileModTimes) {
+		return true
+	}
+
+	for path, modTime := range current {
+		if previous, ok := m.fileModTimes[path]; !ok || !previous.Equal(modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func collectTemplateModTimes() (map[string]time.Time, error) {
+	siteFS := AppConfig.SiteFS()
+	modTimes := make(map[string]time.Time)
+
+	for _, layout := range []string{AppConfig.LayoutPath, AppConfig.MarkdownLayout} {
+		if layout == "" {
+			continue
+		}
+		if info, err := fs.Stat(siteFS, fsPath(layout)); err == nil {
+			modTimes[fsPath(layout)] = info.ModTime()
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	for _, dir := range []string{AppConfig.AppDir, AppConfig.ComponentDir} {
+		err := fs.WalkDir(siteFS, fsPath(dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isTemplateFile(path) {
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				modTimes[path] = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return modTimes, nil
+}
+
+func (m *Marley) loadTemplates() error {
 	startTime := time.Now()
 	m.Logger.InfoLog.Printf("Loading templates...")
 
+	siteFS := AppConfig.SiteFS()
+	layoutPath := fsPath(AppConfig.LayoutPath)
+	markdownLayoutPath := ""
+	if AppConfig.MarkdownLayout != "" && fsPath(AppConfig.MarkdownLayout) != layoutPath {
+		markdownLayoutPath = fsPath(AppConfig.MarkdownLayout)
+	}
+
+	bundles := m.buildBundles()
+	manifest := m.buildAssetManifest()
+	for name, bundle := range bundles {
+		manifest.add(name, bundle.Content)
+	}
+	assets := m.buildAssetTags(manifest)
+	images := newResponsiveImages(manifest)
+
 	var wg sync.WaitGroup
 	errorCh := make(chan error, 2)
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := m.loadComponents(); err != nil {
+		if err := m.loadComponents(siteFS); err != nil {
 			errorCh <- err
 		}
 	}()
@@ -59,7 +219,7 @@ func (m *Marley) LoadTemplates() error {
 	layoutErrCh := make(chan error, 1)
 
 	go func() {
-		layoutContent, err := os.ReadFile(AppConfig.LayoutPath)
+		layoutContent, err := fs.ReadFile(siteFS, layoutPath)
 		if err != nil {
 			layoutErrCh <- fmt.Errorf("failed to load layout template: %w", err)
 			return
@@ -90,14 +250,15 @@ func (m *Marley) LoadTemplates() error {
 		mu            sync.Mutex
 	)
 
-	err := filepath.Walk(AppConfig.AppDir, func(path string, info os.FileInfo, err error) error {
+	err := fs.WalkDir(siteFS, fsPath(AppConfig.AppDir), func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() && filepath.Ext(path) == ".html" &&
-			path != AppConfig.LayoutPath &&
-			!strings.HasPrefix(path, AppConfig.ComponentDir) {
+		if !d.IsDir() && isTemplateFile(path) &&
+			path != layoutPath &&
+			path != markdownLayoutPath &&
+			!fsPathHasPrefix(path, AppConfig.ComponentDir) {
 
 			routePath := getRoutePathFromFile(path, AppConfig.AppDir)
 
@@ -121,7 +282,39 @@ func (m *Marley) LoadTemplates() error {
 		return err
 	}
 
+	frontMatterLayouts := markdownLayoutFiles(siteFS, templatePaths)
+	pagePaths := templatePaths[:0]
+	for _, p := range templatePaths {
+		if !frontMatterLayouts[p] {
+			pagePaths = append(pagePaths, p)
+		}
+	}
+	templatePaths = pagePaths
+
+	routeFiles := make(map[string]string)
+	for _, p := range templatePaths {
+		routePath := getRoutePathFromFile(p, AppConfig.AppDir)
+		if existing, ok := routeFiles[routePath]; ok {
+			err := fmt.Errorf("route conflict: %s and %s both map to %s", existing, p, routePath)
+			m.Logger.ErrorLog.Printf("Failed to scan template directories: %v", err)
+			return err
+		}
+		routeFiles[routePath] = p
+	}
+
+	markdownLayoutContent := layoutContent
+	if markdownLayoutPath != "" {
+		markdownLayoutContent, err = fs.ReadFile(siteFS, markdownLayoutPath)
+		if err != nil {
+			err = fmt.Errorf("failed to load markdown layout template: %w", err)
+			m.Logger.ErrorLog.Printf("%v", err)
+			return err
+		}
+	}
+
 	templates := make(map[string]*template.Template)
+	markdownPages := make(map[string]*MarkdownPage)
+	pageMeta := make(map[string]map[string]string)
 	semaphore := make(chan struct{}, 4)
 	errCh := make(chan error, len(templatePaths))
 
@@ -135,15 +328,46 @@ func (m *Marley) LoadTemplates() error {
 
 			routePath := getRoutePathFromFile(p, AppConfig.AppDir)
 
-			pageContent, err := os.ReadFile(p)
+			pageContent, err := fs.ReadFile(siteFS, p)
 			if err != nil {
 				errCh <- fmt.Errorf("failed to read template %s: %w", p, err)
 				return
 			}
 
-			tmpl := template.New("layout")
+			pageLayout := layoutContent
+			var (
+				markdownPage *MarkdownPage
+				frontMatter  map[string]string
+			)
+			if filepath.Ext(p) != ".md" {
+				frontMatter, pageContent, err = splitPageFrontMatter(pageContent)
+				if err != nil {
+					errCh <- fmt.Errorf("failed to parse front matter in %s: %w", p, err)
+					return
+				}
+			} else {
+				markdownPage, err = RenderMarkdown(pageContent)
+				if err != nil {
+					errCh <- fmt.Errorf("failed to render markdown %s: %w", p, err)
+					return
+				}
+
+				frontMatter = markdownPage.FrontMatter
+				pageContent = []byte(markdownContentTemplate)
+				pageLayout = markdownLayoutContent
+
+				if layout := markdownPage.FrontMatter["layout"]; layout != "" {
+					pageLayout, err = fs.ReadFile(siteFS, markdownLayoutFile(layout))
+					if err != nil {
+						errCh <- fmt.Errorf("failed to load layout %s for %s: %w", layout, p, err)
+						return
+					}
+				}
+			}
+
+			tmpl := template.New("layout").Funcs(templateFuncs(assets, manifest, images, markdownPage))
 
-			_, err = tmpl.Parse(string(layoutContent))
+			_, err = tmpl.Parse(string(pageLayout))
 			if err != nil {
 				errCh <- fmt.Errorf("failed to parse layout template: %w", err)
 				return
@@ -165,6 +389,12 @@ func (m *Marley) LoadTemplates() error {
 
 			mu.Lock()
 			templates[routePath] = tmpl
+			if markdownPage != nil {
+				markdownPages[routePath] = markdownPage
+			}
+			if len(frontMatter) > 0 {
+				pageMeta[routePath] = frontMatter
+			}
 			mu.Unlock()
 
 			m.Logger.InfoLog.Printf("Template loaded: %s → %s", p, routePath)
@@ -182,28 +412,45 @@ func (m *Marley) LoadTemplates() error {
 	}
 
 	m.Templates = templates
-
-	if AppConfig.TemplateCache {
-		m.cacheExpiry = now.Add(m.cacheTTL)
+	m.MarkdownPages = markdownPages
+	m.PageMeta = pageMeta
+	m.PageFiles = routeFiles
+	m.assetManifest = manifest
+	m.bundles = bundles
+
+	if modTimes, err := collectTemplateModTimes(); err == nil {
+		m.fileModTimes = modTimes
+	} else {
+		m.Logger.WarnLog.Printf("Failed to record template modification times: %v", err)
 	}
 
 	elapsedTime := time.Since(startTime)
+
+	m.forceReload = false
+	m.cacheExpiry = startTime.Add(m.cacheTTL)
+	m.stats.Reloads++
+	m.stats.Templates = len(templates)
+	m.stats.TrackedFiles = len(m.fileModTimes)
+	m.stats.LastLoad = startTime
+	m.stats.LastLoadDuration = elapsedTime
+	m.stats.Expiry = m.cacheExpiry
+
 	m.Logger.InfoLog.Printf("Templates loaded successfully in %v", elapsedTime.Round(time.Millisecond))
 
 	return nil
 }
 
-func (m *Marley) loadComponents() error {
+func (m *Marley) loadComponents(siteFS fs.FS) error {
 	componentCache := make(map[string]string)
-	componentDir := AppConfig.ComponentDir
+	componentDir := fsPath(AppConfig.ComponentDir)
 
-	err := filepath.Walk(componentDir, func(path string, info os.FileInfo, err error) error {
+	err := fs.WalkDir(siteFS, componentDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if !info.IsDir() && filepath.Ext(path) == ".html" {
-			componentContent, err := os.ReadFile(path)
+		if !d.IsDir() && filepath.Ext(path) == ".html" {
+			componentContent, err := fs.ReadFile(siteFS, path)
 			if err != nil {
 				return fmt.Errorf("failed to read component %s: %w", path, err)
 			}
@@ -215,7 +462,7 @@ func (m *Marley) loadComponents() error {
 		return nil
 	})
 
-	if err != nil && !os.IsNotExist(err) {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return fmt.Errorf("failed to load components: %w", err)
 	}
 
@@ -223,6 +470,23 @@ func (m *Marley) loadComponents() error {
 	return nil
 }
 
+func templateFuncs(assets *assetTags, manifest *AssetManifest, images *responsiveImages, markdownPage *MarkdownPage) template.FuncMap {
+	return template.FuncMap{
+		"asset":        manifest.URL,
+		"assets":       assets.render,
+		"img":          images.render,
+		"csrfToken":    templateCSRFToken,
+		"csrfField":    templateCSRFField,
+		"can":          templateCan,
+		"markdownPage": func() *MarkdownPage { return markdownPage },
+	}
+}
+
+func isTemplateFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".html" || ext == ".md"
+}
+
 func getRoutePathFromFile(fullPath, basePath string) string {
 	fullPath = filepath.ToSlash(fullPath)
 	basePath = filepath.ToSlash(basePath)
@@ -236,7 +500,7 @@ func getRoutePathFromFile(fullPath, basePath string) string {
 		relativePath = strings.TrimPrefix(fullPath, basePath)
 	}
 
-	relativePath = strings.TrimSuffix(relativePath, ".html")
+	relativePath = strings.TrimSuffix(relativePath, filepath.Ext(relativePath))
 
 	if relativePath == "index" {
 		return "/"
@@ -255,6 +519,10 @@ func getRoutePathFromFile(fullPath, basePath string) string {
 }
 
 func (m *Marley) RenderTemplate(w http.ResponseWriter, route string, data interface{}) error {
+	return m.RenderBlock(w, route, "layout", data)
+}
+
+func (m *Marley) RenderBlock(w http.ResponseWriter, route, block string, data interface{}) error {
 	m.mutex.RLock()
 	tmpl, exists := m.Templates[route]
 	m.mutex.RUnlock()
@@ -263,7 +531,143 @@ func (m *Marley) RenderTemplate(w http.ResponseWriter, route string, data interf
 		return fmt.Errorf("template for route %s not found", route)
 	}
 
-	return tmpl.ExecuteTemplate(w, "layout", data)
+	return tmpl.ExecuteTemplate(w, block, data)
+}
+
+func (m *Marley) AssetManifest() *AssetManifest {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.assetManifest
+}
+
+func (m *Marley) buildAssetManifest() *AssetManifest {
+	staticFS, err := AppConfig.SubFS(AppConfig.StaticDir)
+	if err == nil {
+		var manifest *AssetManifest
+		if manifest, err = BuildAssetManifest(staticFS); err == nil {
+			return manifest
+		}
+	}
+
+	m.Logger.WarnLog.Printf("Failed to fingerprint static assets: %v", err)
+	return NewAssetManifest()
+}
+
+func (m *Marley) Bundle(name string) (*BuiltBundle, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	bundle, ok := m.bundles[name]
+	return bundle, ok
+}
+
+func (m *Marley) Bundles() map[string]*BuiltBundle {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	bundles := make(map[string]*BuiltBundle, len(m.bundles))
+	for name, bundle := range m.bundles {
+		bundles[name] = bundle
+	}
+	return bundles
+}
+
+func (m *Marley) buildBundles() map[string]*BuiltBundle {
+	if len(AppConfig.Bundles) == 0 {
+		return make(map[string]*BuiltBundle)
+	}
+
+	staticFS, err := AppConfig.SubFS(AppConfig.StaticDir)
+	if err == nil {
+		var bundles map[string]*BuiltBundle
+		if bundles, err = BuildBundles(staticFS, AppConfig.Bundles, AppConfig.DevMode); err == nil {
+			for name, bundle := range bundles {
+				m.Logger.InfoLog.Printf("Bundle built: %s (%d inputs, %d bytes)", name, len(bundle.Inputs), len(bundle.Content))
+			}
+			return bundles
+		}
+	}
+
+	m.Logger.ErrorLog.Printf("Failed to build bundles: %v", err)
+	return m.bundles
+}
+
+func (m *Marley) HasTemplate(route string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	_, exists := m.Templates[route]
+	return exists
+}
+
+func (m *Marley) HasBlock(route, block string) bool {
+	m.mutex.RLock()
+	tmpl, exists := m.Templates[route]
+	m.mutex.RUnlock()
+
+	return exists && tmpl.Lookup(block) != nil
+}
+
+func (m *Marley) UsesFuncs(route string, names ...string) bool {
+	return m.usesNode(route, func(n parse.Node) bool {
+		ident, ok := n.(*parse.IdentifierNode)
+		return ok && containsString(names, ident.Ident)
+	})
+}
+
+func (m *Marley) UsesFields(route string, match func(fields []string, args []parse.Node) bool) bool {
+	called := make(map[parse.Node]bool)
+	return m.usesNode(route, func(n parse.Node) bool {
+		if cmd, ok := n.(*parse.CommandNode); ok && len(cmd.Args) > 0 {
+			if fields := nodeFields(cmd.Args[0]); fields != nil {
+				called[cmd.Args[0]] = true
+				return match(fields, cmd.Args[1:])
+			}
+			return false
+		}
+		if called[n] {
+			return false
+		}
+		if fields := nodeFields(n); fields != nil {
+			return match(fields, nil)
+		}
+		return false
+	})
+}
+
+func nodeFields(n parse.Node) []string {
+	switch node := n.(type) {
+	case *parse.FieldNode:
+		return node.Ident
+	case *parse.VariableNode:
+		if len(node.Ident) > 1 && node.Ident[0] == "$" {
+			return node.Ident[1:]
+		}
+	case *parse.ChainNode:
+		return node.Field
+	}
+	return nil
+}
+
+func (m *Marley) usesNode(route string, match func(parse.Node) bool) bool {
+	m.mutex.RLock()
+	tmpl, exists := m.Templates[route]
+	m.mutex.RUnlock()
+
+	if !exists {
+		return false
+	}
+
+	found := false
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walkTemplateNodes(t.Tree.Root, func(n parse.Node) {
+			if !found && match(n) {
+				found = true
+			}
+		})
+	}
+	return found
 }
 
 func (m *Marley) SetCacheTTL(duration time.Duration) {
@@ -277,6 +681,25 @@ func (m *Marley) SetCacheTTL(duration time.Duration) {
 func (m *Marley) InvalidateCache() {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+
+	if m.CacheMode() == CacheModeProd {
+		m.Logger.WarnLog.Printf("Template cache invalidation ignored: cache mode is %q", CacheModeProd)
+		return
+	}
+
+	m.forceReload = true
 	m.cacheExpiry = time.Time{}
 	m.Logger.InfoLog.Printf("Template cache invalidated")
 }
+
+func (m *Marley) CacheStats() TemplateCacheStats {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	stats := m.stats
+	stats.Mode = m.CacheMode()
+	if stats.Mode != CacheModeTTL {
+		stats.Expiry = time.Time{}
+	}
+	return stats
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolvedTemplateCacheMode(t *testing.T) {
+	tests := []struct {
+		mode  string
+		cache bool
+		want  string
+	}{
+		{CacheModeDev, true, CacheModeDev},
+		{CacheModeProd, false, CacheModeProd},
+		{CacheModeTTL, false, CacheModeTTL},
+		{"", true, CacheModeTTL},
+		{"", false, CacheModeDev},
+		{"unknown", true, CacheModeTTL},
+		{"unknown", false, CacheModeDev},
+	}
+
+	for _, tt := range tests {
+		config := Config{TemplateCacheMode: tt.mode, TemplateCache: tt.cache}
+		if got := config.ResolvedTemplateCacheMode(); got != tt.want {
+			t.Errorf("mode %q with TemplateCache=%v = %q, want %q", tt.mode, tt.cache, got, tt.want)
+		}
+	}
+}
+
+func TestMarleyNeedsReload(t *testing.T) {
+	const ttl = time.Minute
+
+	tests := []struct {
+		name    string
+		mode    string
+		changed bool
+		after   time.Duration
+		want    bool
+	}{
+		{"dev unchanged", CacheModeDev, false, 0, true},
+		{"prod changed", CacheModeProd, true, 2 * ttl, false},
+		{"ttl changed before expiry", CacheModeTTL, true, 0, false},
+		{"ttl unchanged after expiry", CacheModeTTL, false, 2 * ttl, false},
+		{"ttl changed after expiry", CacheModeTTL, true, 2 * ttl, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := testSite(nil)
+			withTestSite(t, site)
+			AppConfig.TemplateCacheMode = tt.mode
+			AppConfig.TemplateCacheTTL = ttl
+
+			m := NewMarley(testLogger())
+			if err := m.LoadTemplates(); err != nil {
+				t.Fatalf("LoadTemplates: %v", err)
+			}
+
+			if tt.changed {
+				site["app/index.html"].ModTime = time.Now().Add(time.Hour)
+			}
+			if got := m.needsReload(time.Now().Add(tt.after)); got != tt.want {
+				t.Errorf("needsReload = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
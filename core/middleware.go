@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -29,12 +30,32 @@ func (mc *MiddlewareChain) Then(handler http.Handler) http.Handler {
 	return handler
 }
 
+func ForPath(prefix string, middleware ...MiddlewareFunc) MiddlewareFunc {
+	prefix = normalizePath(prefix)
+	mc := NewMiddlewareChain()
+	for _, m := range middleware {
+		mc.Use(m)
+	}
+
+	return func(next http.Handler) http.Handler {
+		wrapped := mc.Then(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := normalizePath(r.URL.Path)
+			if prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func LoggingMiddleware(logger *AppLogger) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			next.ServeHTTP(w, r)
-			logger.InfoLog.Printf("%s %s %s %v", r.Method, r.URL.Path, r.RemoteAddr, time.Since(start))
+			logger.InfoLog.Printf("%s %s %s %v", r.Method, r.URL.Path, ClientIP(r), time.Since(start))
 		})
 	}
 }
@@ -90,43 +111,6 @@ func CORSMiddleware(allowedOrigins []string) MiddlewareFunc {
 	}
 }
 
-func RateLimitMiddleware(requestsPerMinute int) MiddlewareFunc {
-	type client struct {
-		requests int
-		lastTime time.Time
-	}
-
-	clients := make(map[string]*client)
-	ticker := time.NewTicker(time.Minute)
-
-	go func() {
-		for range ticker.C {
-			for ip := range clients {
-				clients[ip].requests = 0
-			}
-		}
-	}()
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.RemoteAddr
-			c, exists := clients[ip]
-			if !exists {
-				c = &client{requests: 0, lastTime: time.Now()}
-				clients[ip] = c
-			}
-
-			if c.requests >= requestsPerMinute {
-				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
-				return
-			}
-
-			c.requests++
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
 func ContextMiddleware(key interface{}, value interface{}) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,400 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const oidcSessionKey = "_oidc"
+
+type OIDCClient struct {
+	mutex       sync.Mutex
+	provider    *oidcProvider
+	keys        *JWTKeySet
+	keysFetched time.Time
+	client      *http.Client
+	logger      *AppLogger
+}
+
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+}
+
+type oidcLogin struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func NewOIDCClient(logger *AppLogger) *OIDCClient {
+	return &OIDCClient{
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+func (r *Router) addOIDCRoutes() {
+	if AppConfig.OIDCMockProvider {
+		r.addMockOIDCRoutes()
+	}
+	if oidcIssuer() == "" {
+		return
+	}
+
+	if r.OIDC == nil {
+		r.OIDC = NewOIDCClient(r.Logger)
+	}
+
+	r.AddRoute(AppConfig.OIDCLoginPath, r.OIDC.handleLogin)
+	r.AddRoute(AppConfig.OIDCCallbackPath, r.OIDC.handleCallback)
+	r.AddRoute(AppConfig.OIDCLogoutPath, r.OIDC.handleLogout)
+
+	r.Logger.InfoLog.Printf("OIDC routes registered: %s, %s, %s (issuer: %s)",
+		AppConfig.OIDCLoginPath, AppConfig.OIDCCallbackPath, AppConfig.OIDCLogoutPath, oidcIssuer())
+}
+
+func RequireLogin() MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetUser(r) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if strings.HasPrefix(r.URL.Path, "/api") {
+				RenderError(w, "Login required", http.StatusUnauthorized)
+				return
+			}
+
+			login := AppConfig.OIDCLoginPath + "?return_to=" + url.QueryEscape(r.URL.RequestURI())
+			http.Redirect(w, r, login, http.StatusFound)
+		})
+	}
+}
+
+func (oc *OIDCClient) handleLogin(w http.ResponseWriter, r *http.Request) {
+	session := GetSession(r)
+	if session == nil {
+		oc.logger.ErrorLog.Printf("OIDC login needs SessionMiddleware")
+		http.Error(w, "Login is not available", http.StatusInternalServerError)
+		return
+	}
+
+	provider, err := oc.discover()
+	if err != nil {
+		oc.logger.ErrorLog.Printf("OIDC discovery failed: %v", err)
+		http.Error(w, "Login provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	login := oidcLogin{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		Verifier: randomToken(),
+		ReturnTo: localReturnPath(r.URL.Query().Get("return_to")),
+	}
+	data, _ := json.Marshal(login)
+	session.Set(oidcSessionKey, string(data))
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {AppConfig.OIDCClientID},
+		"redirect_uri":          {oidcRedirectURL(r)},
+		"scope":                 {strings.Join(AppConfig.OIDCScopes, " ")},
+		"state":                 {login.State},
+		"nonce":                 {login.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	target := provider.AuthorizationEndpoint
+	if strings.Contains(target, "?") {
+		target += "&" + params.Encode()
+	} else {
+		target += "?" + params.Encode()
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (oc *OIDCClient) handleCallback(w http.ResponseWriter, r *http.Request) {
+	session := GetSession(r)
+	if session == nil {
+		oc.logger.ErrorLog.Printf("OIDC callback needs SessionMiddleware")
+		http.Error(w, "Login is not available", http.StatusInternalServerError)
+		return
+	}
+
+	var login oidcLogin
+	if err := json.Unmarshal([]byte(session.GetString(oidcSessionKey)), &login); err != nil || login.State == "" {
+		http.Error(w, "Login session expired, please try again", http.StatusBadRequest)
+		return
+	}
+	session.Delete(oidcSessionKey)
+
+	query := r.URL.Query()
+	if query.Get("state") != login.State {
+		oc.logger.WarnLog.Printf("OIDC callback state mismatch")
+		http.Error(w, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	if providerErr := query.Get("error"); providerErr != "" {
+		oc.logger.WarnLog.Printf("OIDC provider returned %s: %s", providerErr, query.Get("error_description"))
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+
+	claims, err := oc.exchange(r, query.Get("code"), login)
+	if err != nil {
+		oc.logger.WarnLog.Printf("OIDC login failed: %v", err)
+		http.Error(w, "Login failed", http.StatusUnauthorized)
+		return
+	}
+
+	user := userFromClaims(claims)
+	LoginUser(r, user)
+	oc.logger.InfoLog.Printf("User %s logged in via OIDC", user.ID)
+
+	http.Redirect(w, r, login.ReturnTo, http.StatusSeeOther)
+}
+
+func (oc *OIDCClient) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state, ok := r.Context().Value(csrfKey{}).(*csrfState)
+	if !ok {
+		state = &csrfState{w: w, req: r, logger: oc.logger}
+	}
+	if err := checkCSRF(r, state); err != nil {
+		oc.logger.WarnLog.Printf("OIDC logout rejected: %v", err)
+		http.Error(w, "Forbidden - invalid CSRF token", http.StatusForbidden)
+		return
+	}
+
+	LogoutUser(r)
+	http.Redirect(w, r, localReturnPath(AppConfig.OIDCPostLogoutPath), http.StatusSeeOther)
+}
+
+func (oc *OIDCClient) exchange(r *http.Request, code string, login oidcLogin) (Claims, error) {
+	if code == "" {
+		return nil, errors.New("callback has no code")
+	}
+
+	provider, err := oc.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcRedirectURL(r)},
+		"client_id":     {AppConfig.OIDCClientID},
+		"code_verifier": {login.Verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if AppConfig.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(AppConfig.OIDCClientID), url.QueryEscape(AppConfig.OIDCClientSecret))
+	}
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token oidcTokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("token endpoint returned %d %s: %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	claims, err := oc.verifyIDToken(token.IDToken, provider)
+	if err != nil {
+		return nil, err
+	}
+	if claims.String("nonce") != login.Nonce {
+		return nil, errors.New("id_token nonce does not match")
+	}
+	if len(claims.Audience()) > 1 && claims.String("azp") != AppConfig.OIDCClientID {
+		return nil, errors.New("id_token azp does not match the client")
+	}
+	return claims, nil
+}
+
+func (oc *OIDCClient) verifyIDToken(idToken string, provider *oidcProvider) (Claims, error) {
+	options := JWTOptions{
+		Issuer:     provider.Issuer,
+		Audience:   []string{AppConfig.OIDCClientID},
+		ClockSkew:  AppConfig.JWTClockSkew,
+		Algorithms: []string{"RS256", "ES256"},
+	}
+
+	keys, err := oc.jwks(provider, false)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := VerifyJWT(idToken, keys, options)
+	if errors.Is(err, ErrTokenSignature) {
+		if keys, err = oc.jwks(provider, true); err != nil {
+			return nil, err
+		}
+		claims, err = VerifyJWT(idToken, keys, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	return claims, nil
+}
+
+func (oc *OIDCClient) discover() (*oidcProvider, error) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	if oc.provider != nil {
+		return oc.provider, nil
+	}
+
+	issuer := oidcIssuer()
+	var provider oidcProvider
+	if err := oc.getJSON(strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	oc.provider = &provider
+	return oc.provider, nil
+}
+
+func (oc *OIDCClient) jwks(provider *oidcProvider, refresh bool) (*JWTKeySet, error) {
+	oc.mutex.Lock()
+	defer oc.mutex.Unlock()
+
+	if oc.keys != nil && (!refresh || time.Since(oc.keysFetched) < time.Minute) {
+		return oc.keys, nil
+	}
+
+	var raw json.RawMessage
+	if err := oc.getJSON(provider.JWKSURI, &raw); err != nil {
+		return nil, err
+	}
+	keys, err := ParseJWKS(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	oc.keys = keys
+	oc.keysFetched = time.Now()
+	return keys, nil
+}
+
+func (oc *OIDCClient) getJSON(target string, v interface{}) error {
+	resp, err := oc.client.Get(target)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: status %d", target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", target, err)
+	}
+	return nil
+}
+
+func userFromClaims(claims Claims) *User {
+	user := &User{
+		ID:     claims.Subject(),
+		Name:   claims.String("name"),
+		Email:  claims.String("email"),
+		Claims: claims,
+	}
+
+	switch roles := claims[AppConfig.OIDCRolesClaim].(type) {
+	case string:
+		user.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				user.Roles = append(user.Roles, s)
+			}
+		}
+	}
+	return user
+}
+
+func oidcIssuer() string {
+	if AppConfig.OIDCIssuer != "" {
+		return AppConfig.OIDCIssuer
+	}
+	if mockOIDCEnabled() {
+		return mockOIDCIssuer()
+	}
+	return ""
+}
+
+func oidcRedirectURL(r *http.Request) string {
+	if AppConfig.OIDCRedirectURL != "" {
+		return AppConfig.OIDCRedirectURL
+	}
+	return siteBaseURL(r) + AppConfig.OIDCCallbackPath
+}
+
+func localReturnPath(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(fmt.Sprintf("failed to generate random token: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const mockOIDCPath = "/_oidc/mock"
+
+type mockOIDCProvider struct {
+	mutex sync.Mutex
+	key   *rsa.PrivateKey
+	codes map[string]mockOIDCCode
+}
+
+type mockOIDCCode struct {
+	RedirectURI string
+	Challenge   string
+	Nonce       string
+	Email       string
+	Name        string
+	Roles       []string
+	Expires     time.Time
+}
+
+var mockOIDC = &mockOIDCProvider{codes: make(map[string]mockOIDCCode)}
+
+var mockOIDCLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Mock sign-in</title></head>
+<body style="font-family: sans-serif; max-width: 24rem; margin: 4rem auto">
+<h1>Mock sign-in</h1>
+<p>This is the built-in OIDC mock provider. Any email is accepted.</p>
+<form method="post">
+{{range $name, $values := .Params}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">{{end}}{{end}}
+<p><label>Email<br><input name="email" value="dev@example.com"></label></p>
+<p><label>Name<br><input name="name" value="Dev User"></label></p>
+<p><label>Roles<br><input name="roles" value=""></label></p>
+<button>Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+func (r *Router) addMockOIDCRoutes() {
+	if !AppConfig.DevMode {
+		r.Logger.ErrorLog.Printf("OIDC mock provider is not registered: it lets anyone sign in and only runs in dev mode")
+		return
+	}
+
+	r.AddRoute(mockOIDCPath+"/.well-known/openid-configuration", mockOIDC.handleDiscovery)
+	r.AddRoute(mockOIDCPath+"/authorize", mockOIDC.handleAuthorize)
+	r.AddRoute(mockOIDCPath+"/token", mockOIDC.handleToken)
+	r.AddRoute(mockOIDCPath+"/jwks", mockOIDC.handleJWKS)
+
+	r.Logger.InfoLog.Printf("OIDC mock provider registered: %s", mockOIDCIssuer())
+}
+
+func mockOIDCEnabled() bool {
+	return AppConfig.OIDCMockProvider && AppConfig.DevMode
+}
+
+func mockOIDCIssuer() string {
+	return "http://localhost:" + AppConfig.Port + mockOIDCPath
+}
+
+func (p *mockOIDCProvider) signingKey() *rsa.PrivateKey {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.key == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(fmt.Sprintf("failed to generate mock OIDC key: %v", err))
+		}
+		p.key = key
+	}
+	return p.key
+}
+
+func (p *mockOIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	issuer := mockOIDCIssuer()
+	RenderJSON(w, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	}, http.StatusOK)
+}
+
+func (p *mockOIDCProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	key := p.signingKey()
+	RenderJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}, http.StatusOK)
+}
+
+func (p *mockOIDCProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{}
+	for _, name := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"} {
+		params.Set(name, r.Form.Get(name))
+	}
+
+	if params.Get("client_id") != AppConfig.OIDCClientID {
+		http.Error(w, "Unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "Invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if params.Get("response_type") != "code" || params.Get("code_challenge_method") != "S256" || params.Get("code_challenge") == "" {
+		http.Error(w, "The mock provider only supports the code flow with S256 PKCE", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		mockOIDCLoginPage.Execute(w, map[string]interface{}{"Params": params})
+		return
+	}
+
+	email := strings.TrimSpace(r.PostForm.Get("email"))
+	if email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	code := randomToken()
+	p.mutex.Lock()
+	p.codes[code] = mockOIDCCode{
+		RedirectURI: params.Get("redirect_uri"),
+		Challenge:   params.Get("code_challenge"),
+		Nonce:       params.Get("nonce"),
+		Email:       email,
+		Name:        r.PostForm.Get("name"),
+		Roles:       strings.FieldsFunc(r.PostForm.Get("roles"), func(c rune) bool { return c == ',' || c == ' ' }),
+		Expires:     time.Now().Add(time.Minute),
+	}
+	p.mutex.Unlock()
+
+	query := redirectURI.Query()
+	query.Set("code", code)
+	query.Set("state", params.Get("state"))
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (p *mockOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		RenderJSON(w, map[string]string{"error": "invalid_request"}, http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		RenderJSON(w, map[string]string{"error": "unsupported_grant_type"}, http.StatusBadRequest)
+		return
+	}
+
+	clientID, secret, hasBasic := r.BasicAuth()
+	if hasBasic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != AppConfig.OIDCClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(AppConfig.OIDCClientSecret)) != 1 {
+		RenderJSON(w, map[string]string{"error": "invalid_client"}, http.StatusUnauthorized)
+		return
+	}
+
+	codeValue := r.PostForm.Get("code")
+	p.mutex.Lock()
+	code, ok := p.codes[codeValue]
+	delete(p.codes, codeValue)
+	p.mutex.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(code.Expires):
+		RenderJSON(w, map[string]string{"error": "invalid_grant", "error_description": "unknown or expired code"}, http.StatusBadRequest)
+		return
+	case code.RedirectURI != r.PostForm.Get("redirect_uri"):
+		RenderJSON(w, map[string]string{"error": "invalid_grant", "error_description": "redirect_uri does not match"}, http.StatusBadRequest)
+		return
+	case base64.RawURLEncoding.EncodeToString(verifier[:]) != code.Challenge:
+		RenderJSON(w, map[string]string{"error": "invalid_grant", "error_description": "code_verifier does not match"}, http.StatusBadRequest)
+		return
+	}
+
+	subject := sha256.Sum256([]byte(strings.ToLower(code.Email)))
+	now := time.Now()
+	idToken, err := SignJWT(Claims{
+		"iss":            mockOIDCIssuer(),
+		"sub":            fmt.Sprintf("%x", subject[:8]),
+		"aud":            AppConfig.OIDCClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          code.Nonce,
+		"email":          code.Email,
+		"email_verified": true,
+		"name":           code.Name,
+		"roles":          code.Roles,
+	}, JWTKey{ID: "mock", Algorithm: "RS256", Key: p.signingKey()})
+	if err != nil {
+		RenderJSON(w, map[string]string{"error": "server_error"}, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	RenderJSON(w, map[string]interface{}{
+		"access_token": randomToken(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	}, http.StatusOK)
+}
@@ -0,0 +1,330 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type oidcTestServer struct {
+	*httptest.Server
+	t      *testing.T
+	client *http.Client
+}
+
+func newOIDCTestServer(t *testing.T) *oidcTestServer {
+	t.Helper()
+	withTestConfig(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	AppConfig.DevMode = true
+	AppConfig.Port = port
+	AppConfig.BaseURL = ""
+	AppConfig.OIDCIssuer = ""
+	AppConfig.OIDCMockProvider = true
+	AppConfig.OIDCClientID = "test-client"
+	AppConfig.OIDCClientSecret = "test-secret"
+	AppConfig.OIDCRedirectURL = ""
+	AppConfig.SessionSecret = "0123456789abcdef0123456789abcdef"
+
+	mockOIDC.mutex.Lock()
+	mockOIDC.codes = make(map[string]mockOIDCCode)
+	mockOIDC.mutex.Unlock()
+
+	logger := testLogger()
+	router := NewRouter(logger)
+	router.Use(SessionMiddleware(NewMemorySessionStore(), logger))
+	router.addOIDCRoutes()
+	router.API("/api/me", func(ctx *APIContext) {
+		ctx.Success(GetUser(ctx.Request), http.StatusOK)
+	})
+	router.API("/api/csrf", func(ctx *APIContext) {
+		ctx.Success(CSRFToken(ctx.Request), http.StatusOK)
+	})
+
+	server := httptest.NewUnstartedServer(router)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &oidcTestServer{Server: server, t: t, client: client}
+}
+
+func (s *oidcTestServer) url(path string) string {
+	return "http://localhost:" + AppConfig.Port + path
+}
+
+func (s *oidcTestServer) do(method, target string, form url.Values) *http.Response {
+	s.t.Helper()
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		s.t.Fatalf("new request: %v", err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, target, err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func (s *oidcTestServer) location(resp *http.Response, status int) *url.URL {
+	s.t.Helper()
+	if resp.StatusCode != status {
+		s.t.Fatalf("%s: status %d, want %d", resp.Request.URL.Path, resp.StatusCode, status)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		s.t.Fatalf("%s: no Location header: %v", resp.Request.URL.Path, err)
+	}
+	return location
+}
+
+func (s *oidcTestServer) authorize(returnTo string) (*url.URL, url.Values) {
+	s.t.Helper()
+
+	authorize := s.location(s.do(http.MethodGet, s.url("/auth/login?return_to="+url.QueryEscape(returnTo)), nil), http.StatusFound)
+	params := authorize.Query()
+	for _, name := range []string{"state", "nonce", "code_challenge"} {
+		if params.Get(name) == "" {
+			s.t.Fatalf("authorize request has no %s: %s", name, authorize)
+		}
+	}
+	if params.Get("code_challenge_method") != "S256" {
+		s.t.Fatalf("code_challenge_method = %q, want S256", params.Get("code_challenge_method"))
+	}
+
+	form := url.Values{"email": {"dev@example.com"}, "name": {"Dev"}, "roles": {"editor"}}
+	callback := s.location(s.do(http.MethodPost, authorize.String(), form), http.StatusFound)
+	if callback.Query().Get("state") != params.Get("state") {
+		s.t.Fatalf("callback state = %q, want %q", callback.Query().Get("state"), params.Get("state"))
+	}
+	return callback, params
+}
+
+func (s *oidcTestServer) currentUser() *User {
+	s.t.Helper()
+
+	resp, err := s.client.Get(s.url("/api/me"))
+	if err != nil {
+		s.t.Fatalf("GET /api/me: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data *User `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		s.t.Fatalf("decode /api/me: %v", err)
+	}
+	return body.Data
+}
+
+func (s *oidcTestServer) csrfToken() string {
+	s.t.Helper()
+
+	resp, err := s.client.Get(s.url("/api/csrf"))
+	if err != nil {
+		s.t.Fatalf("GET /api/csrf: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Data == "" {
+		s.t.Fatalf("decode /api/csrf: %q, %v", body.Data, err)
+	}
+	return body.Data
+}
+
+func TestOIDCMockDiscovery(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	resp, err := s.client.Get(s.url(mockOIDCPath + "/.well-known/openid-configuration"))
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var provider oidcProvider
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		t.Fatalf("decode discovery: %v", err)
+	}
+	if provider.Issuer != mockOIDCIssuer() {
+		t.Errorf("issuer = %q, want %q", provider.Issuer, mockOIDCIssuer())
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		t.Errorf("discovery is missing endpoints: %+v", provider)
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	if user := s.currentUser(); user != nil {
+		t.Fatalf("user before login = %+v, want none", user)
+	}
+
+	callback, _ := s.authorize("/dashboard?tab=1")
+	returned := s.location(s.do(http.MethodGet, callback.String(), nil), http.StatusSeeOther)
+	if returned.RequestURI() != "/dashboard?tab=1" {
+		t.Errorf("redirect after login = %q, want /dashboard?tab=1", returned)
+	}
+
+	user := s.currentUser()
+	if user == nil {
+		t.Fatal("no user in session after login")
+	}
+	if user.Email != "dev@example.com" || user.Name != "Dev" || !user.HasRole("editor") || user.ID == "" {
+		t.Errorf("session user = %+v", user)
+	}
+
+	replay := s.do(http.MethodGet, callback.String(), nil)
+	if replay.StatusCode != http.StatusBadRequest {
+		t.Errorf("replayed callback status = %d, want %d", replay.StatusCode, http.StatusBadRequest)
+	}
+
+	rejected := []struct {
+		name   string
+		method string
+		form   url.Values
+		status int
+	}{
+		{"GET", http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{"POST without token", http.MethodPost, url.Values{}, http.StatusForbidden},
+		{"POST with forged token", http.MethodPost, url.Values{"csrf_token": {"forged"}}, http.StatusForbidden},
+	}
+	for _, tt := range rejected {
+		if resp := s.do(tt.method, s.url("/auth/logout"), tt.form); resp.StatusCode != tt.status {
+			t.Errorf("logout %s: status = %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+		if s.currentUser() == nil {
+			t.Fatalf("logout %s signed the user out", tt.name)
+		}
+	}
+
+	form := url.Values{"csrf_token": {s.csrfToken()}}
+	logout := s.location(s.do(http.MethodPost, s.url("/auth/logout"), form), http.StatusSeeOther)
+	if logout.Path != "/" {
+		t.Errorf("redirect after logout = %q, want /", logout)
+	}
+	if user := s.currentUser(); user != nil {
+		t.Errorf("user after logout = %+v, want none", user)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	callback, _ := s.authorize("/")
+	query := callback.Query()
+	query.Set("state", "forged")
+	callback.RawQuery = query.Encode()
+
+	resp := s.do(http.MethodGet, callback.String(), nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if user := s.currentUser(); user != nil {
+		t.Errorf("user after forged state = %+v, want none", user)
+	}
+}
+
+func TestOIDCMockTokenChecksVerifier(t *testing.T) {
+	s := newOIDCTestServer(t)
+
+	verifier := "correct-verifier"
+	challenge := sha256.Sum256([]byte(verifier))
+	redirectURI := s.url("/auth/callback")
+	authorize := s.url(mockOIDCPath + "/authorize?" + url.Values{
+		"response_type":         {"code"},
+		"client_id":             {AppConfig.OIDCClientID},
+		"redirect_uri":          {redirectURI},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}.Encode())
+
+	tests := []struct {
+		name     string
+		verifier string
+		secret   string
+		status   int
+	}{
+		{"bad verifier", "wrong-verifier", AppConfig.OIDCClientSecret, http.StatusBadRequest},
+		{"bad client secret", verifier, "wrong-secret", http.StatusUnauthorized},
+		{"valid", verifier, AppConfig.OIDCClientSecret, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callback := s.location(s.do(http.MethodPost, authorize, url.Values{"email": {"dev@example.com"}}), http.StatusFound)
+
+			req, _ := http.NewRequest(http.MethodPost, s.url(mockOIDCPath+"/token"), strings.NewReader(url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {callback.Query().Get("code")},
+				"redirect_uri":  {redirectURI},
+				"code_verifier": {tt.verifier},
+			}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(AppConfig.OIDCClientID, tt.secret)
+
+			resp, err := s.client.Do(req)
+			if err != nil {
+				t.Fatalf("token request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestOIDCMockRequiresDevMode(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.DevMode = false
+	AppConfig.OIDCMockProvider = true
+	AppConfig.OIDCIssuer = ""
+
+	router := NewRouter(testLogger())
+	router.addOIDCRoutes()
+
+	if len(router.Routes) != 0 {
+		t.Errorf("registered %d routes outside dev mode, want none", len(router.Routes))
+	}
+	if issuer := oidcIssuer(); issuer != "" {
+		t.Errorf("oidcIssuer() = %q outside dev mode, want empty", issuer)
+	}
+}
@@ -0,0 +1,421 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template/parse"
+	"time"
+)
+
+type PageCachePolicy struct {
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	VaryHeaders          []string
+	VaryCookies          []string
+}
+
+func (p PageCachePolicy) Enabled() bool {
+	return p.TTL > 0
+}
+
+func (p PageCachePolicy) perRequestField(fields []string, args []parse.Node) bool {
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[0] {
+	case "User", "Session", "Flash", "Locals", "Local", "Form", "Errors":
+		return true
+	case "Request":
+		if len(fields) == 1 {
+			return true
+		}
+		switch fields[1] {
+		case "Method", "Path":
+			return false
+		case "Header":
+			name, ok := literalArg(args)
+			return !ok || !containsFold(p.VaryHeaders, name)
+		case "Cookie":
+			name, ok := literalArg(args)
+			return !ok || !containsString(p.VaryCookies, name)
+		}
+		return true
+	}
+	return false
+}
+
+func literalArg(args []parse.Node) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	str, ok := args[0].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return str.Text, true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p PageCachePolicy) cacheKey(req *http.Request) string {
+	var sb strings.Builder
+	sb.WriteString(normalizePath(req.URL.Path))
+	if req.URL.RawQuery != "" {
+		sb.WriteString("?")
+		sb.WriteString(req.URL.RawQuery)
+	}
+
+	for _, name := range p.VaryHeaders {
+		sb.WriteString("|h:")
+		sb.WriteString(strings.ToLower(name))
+		sb.WriteString("=")
+		sb.WriteString(req.Header.Get(name))
+	}
+
+	for _, name := range p.VaryCookies {
+		sb.WriteString("|c:")
+		sb.WriteString(name)
+		sb.WriteString("=")
+		if cookie, err := req.Cookie(name); err == nil {
+			sb.WriteString(cookie.Value)
+		}
+	}
+
+	return sb.String()
+}
+
+type PageCacheStats struct {
+	Entries     int    `json:"entries"`
+	Hits        uint64 `json:"hits"`
+	StaleHits   uint64 `json:"stale_hits"`
+	Misses      uint64 `json:"misses"`
+	Collapsed   uint64 `json:"collapsed"`
+	Revalidated uint64 `json:"revalidated"`
+}
+
+type PageCache struct {
+	MaxEntries int
+	mutex      sync.Mutex
+	entries    map[string]*pageCacheEntry
+	inflight   map[string]*pageRender
+	generation uint64
+	stats      PageCacheStats
+	logger     *AppLogger
+}
+
+type pageCacheEntry struct {
+	path         string
+	status       int
+	header       http.Header
+	body         []byte
+	created      time.Time
+	ttl          time.Duration
+	swr          time.Duration
+	revalidating bool
+}
+
+type pageRender struct {
+	done chan struct{}
+	resp *responseBuffer
+}
+
+func NewPageCache(maxEntries int, logger *AppLogger) *PageCache {
+	return &PageCache{
+		MaxEntries: maxEntries,
+		entries:    make(map[string]*pageCacheEntry),
+		inflight:   make(map[string]*pageRender),
+		logger:     logger,
+	}
+}
+
+func (pc *PageCache) Serve(w http.ResponseWriter, req *http.Request, policy PageCachePolicy, render http.HandlerFunc) {
+	key := policy.cacheKey(req)
+	now := time.Now()
+
+	pc.mutex.Lock()
+	if entry, ok := pc.entries[key]; ok {
+		age := now.Sub(entry.created)
+		if age < entry.ttl {
+			pc.stats.Hits++
+			pc.mutex.Unlock()
+			entry.writeTo(w, "HIT", age)
+			return
+		}
+
+		if age < entry.ttl+entry.swr {
+			pc.stats.StaleHits++
+			if !entry.revalidating {
+				entry.revalidating = true
+				go pc.revalidate(key, req.Clone(context.WithoutCancel(req.Context())), policy, render)
+			}
+			pc.mutex.Unlock()
+			entry.writeTo(w, "STALE", age)
+			return
+		}
+	}
+	pc.stats.Misses++
+	pc.mutex.Unlock()
+
+	resp := pc.renderOnce(key, req, policy, render)
+	resp.writeTo(w, "MISS")
+}
+
+func (pc *PageCache) revalidate(key string, req *http.Request, policy PageCachePolicy, render http.HandlerFunc) {
+	resp := pc.renderOnce(key, req, policy, render)
+
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	if resp.status == http.StatusOK {
+		pc.stats.Revalidated++
+		return
+	}
+
+	if entry, ok := pc.entries[key]; ok {
+		entry.revalidating = false
+	}
+	if pc.logger != nil {
+		pc.logger.WarnLog.Printf("Page cache revalidation for %s returned status %d", req.URL.Path, resp.status)
+	}
+}
+
+func (pc *PageCache) renderOnce(key string, req *http.Request, policy PageCachePolicy, render http.HandlerFunc) *responseBuffer {
+	pc.mutex.Lock()
+	if call, ok := pc.inflight[key]; ok {
+		pc.stats.Collapsed++
+		pc.mutex.Unlock()
+		<-call.done
+		return call.resp
+	}
+
+	call := &pageRender{done: make(chan struct{})}
+	pc.inflight[key] = call
+	generation := pc.generation
+	pc.mutex.Unlock()
+
+	resp := newResponseBuffer()
+	defer func() {
+		call.resp = resp
+
+		pc.mutex.Lock()
+		delete(pc.inflight, key)
+		if resp.status == http.StatusOK && generation == pc.generation {
+			pc.store(key, &pageCacheEntry{
+				path:    normalizePath(req.URL.Path),
+				status:  resp.status,
+				header:  resp.header.Clone(),
+				body:    resp.body.Bytes(),
+				created: time.Now(),
+				ttl:     policy.TTL,
+				swr:     policy.StaleWhileRevalidate,
+			})
+		}
+		pc.mutex.Unlock()
+
+		close(call.done)
+	}()
+
+	render(resp, req)
+	return resp
+}
+
+func (pc *PageCache) store(key string, entry *pageCacheEntry) {
+	if _, exists := pc.entries[key]; !exists && pc.MaxEntries > 0 && len(pc.entries) >= pc.MaxEntries {
+		pc.evict(entry.created)
+	}
+	pc.entries[key] = entry
+	pc.stats.Entries = len(pc.entries)
+}
+
+func (pc *PageCache) evict(now time.Time) {
+	for key, entry := range pc.entries {
+		if now.Sub(entry.created) >= entry.ttl+entry.swr {
+			delete(pc.entries, key)
+		}
+	}
+
+	if len(pc.entries) < pc.MaxEntries {
+		return
+	}
+
+	keys := make([]string, 0, len(pc.entries))
+	for key := range pc.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return pc.entries[keys[i]].created.Before(pc.entries[keys[j]].created)
+	})
+
+	for _, key := range keys[:len(keys)-pc.MaxEntries+1] {
+		delete(pc.entries, key)
+	}
+}
+
+func (pc *PageCache) Purge(path string) int {
+	path = normalizePath(path)
+
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	purged := 0
+	for key, entry := range pc.entries {
+		if entry.path == path {
+			delete(pc.entries, key)
+			purged++
+		}
+	}
+	pc.generation++
+	pc.stats.Entries = len(pc.entries)
+
+	return purged
+}
+
+func (pc *PageCache) PurgeAll() int {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	purged := len(pc.entries)
+	pc.entries = make(map[string]*pageCacheEntry)
+	pc.generation++
+	pc.stats.Entries = 0
+
+	return purged
+}
+
+func (pc *PageCache) Stats() PageCacheStats {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	return pc.stats
+}
+
+func (e *pageCacheEntry) writeTo(w http.ResponseWriter, status string, age time.Duration) {
+	for name, values := range e.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("X-Cache", status)
+	w.Header().Set("Age", strconv.Itoa(int(age.Seconds())))
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (rb *responseBuffer) Header() http.Header {
+	return rb.header
+}
+
+func (rb *responseBuffer) WriteHeader(status int) {
+	if rb.wroteHeader {
+		return
+	}
+	rb.status = status
+	rb.wroteHeader = true
+}
+
+func (rb *responseBuffer) Write(p []byte) (int, error) {
+	rb.wroteHeader = true
+	return rb.body.Write(p)
+}
+
+func (rb *responseBuffer) writeTo(w http.ResponseWriter, cacheStatus string) {
+	for name, values := range rb.header {
+		w.Header()[name] = values
+	}
+	if cacheStatus != "" {
+		w.Header().Set("X-Cache", cacheStatus)
+	}
+	w.WriteHeader(rb.status)
+	w.Write(rb.body.Bytes())
+}
+
+func (r *Router) pageCachePolicy(route string) PageCachePolicy {
+	policy := PageCachePolicy{
+		TTL:                  AppConfig.PageCacheTTL,
+		StaleWhileRevalidate: AppConfig.PageCacheStaleWhileRevalidate,
+		VaryHeaders:          AppConfig.PageCacheVaryHeaders,
+		VaryCookies:          AppConfig.PageCacheVaryCookies,
+	}
+
+	meta := r.Marley.PageMeta[route]
+	if meta == nil {
+		return policy
+	}
+
+	switch strings.ToLower(meta["cache"]) {
+	case "false", "off", "no":
+		return PageCachePolicy{}
+	}
+
+	if value := meta["cache_ttl"]; value != "" {
+		if ttl, err := time.ParseDuration(value); err == nil {
+			policy.TTL = ttl
+		} else {
+			r.Logger.WarnLog.Printf("Invalid cache_ttl %q for %s: %v", value, route, err)
+		}
+	}
+
+	if value := meta["cache_swr"]; value != "" {
+		if swr, err := time.ParseDuration(value); err == nil {
+			policy.StaleWhileRevalidate = swr
+		} else {
+			r.Logger.WarnLog.Printf("Invalid cache_swr %q for %s: %v", value, route, err)
+		}
+	}
+
+	if value := meta["cache_vary_headers"]; value != "" {
+		policy.VaryHeaders = splitList(value)
+	}
+
+	if value := meta["cache_vary_cookies"]; value != "" {
+		policy.VaryCookies = splitList(value)
+	}
+
+	return policy
+}
+
+func (r *Router) PurgePage(path string) int {
+	purged := r.PageCache.Purge(path)
+	r.Logger.InfoLog.Printf("Page cache purged for %s (%d entries)", normalizePath(path), purged)
+	return purged
+}
+
+func (r *Router) PurgeAllPages() int {
+	purged := r.PageCache.PurgeAll()
+	r.Logger.InfoLog.Printf("Page cache purged (%d entries)", purged)
+	return purged
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPageCachePerRequestFields(t *testing.T) {
+	policy := PageCachePolicy{
+		TTL:         time.Minute,
+		VaryHeaders: []string{"Accept-Language"},
+		VaryCookies: []string{"theme"},
+	}
+
+	tests := []struct {
+		page       string
+		perRequest bool
+	}{
+		{`{{.Params.slug}}`, false},
+		{`{{.Request.Path}}`, false},
+		{`{{.Request.Header "Accept-Language"}}`, false},
+		{`{{.Request.Header "accept-language"}}`, false},
+		{`{{$.Request.Header "Accept-Language"}}`, false},
+		{`{{.Request.Cookie "theme"}}`, false},
+		{`{{.Request.Header "Authorization"}}`, true},
+		{`{{.Request.Cookie "session"}}`, true},
+		{`{{.Request.Cookie "Theme"}}`, true},
+		{`{{$name := "Accept-Language"}}{{.Request.Header $name}}`, true},
+		{`{{"Accept-Language" | .Request.Header}}`, true},
+		{`{{.Request.ClientIP}}`, true},
+		{`{{.User.Name}}`, true},
+		{`{{with .Session}}{{.ID}}{{end}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			m := &Marley{Templates: map[string]*template.Template{
+				"/": template.Must(template.New("page").Parse(tt.page)),
+			}}
+			if got := m.UsesFields("/", policy.perRequestField); got != tt.perRequest {
+				t.Errorf("per request = %v, want %v", got, tt.perRequest)
+			}
+		})
+	}
+}
+
+func TestPageCacheServe(t *testing.T) {
+	policy := PageCachePolicy{TTL: time.Minute, StaleWhileRevalidate: time.Minute}
+
+	tests := []struct {
+		name  string
+		age   time.Duration
+		cache string
+		body  string
+	}{
+		{"first request", 0, "MISS", "1"},
+		{"fresh", 0, "HIT", "1"},
+		{"stale", 90 * time.Second, "STALE", "1"},
+		{"revalidated", 0, "HIT", "2"},
+		{"expired", 3 * time.Minute, "MISS", "3"},
+	}
+
+	cache := NewPageCache(0, testLogger())
+	var renders atomic.Int32
+	render := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strconv.Itoa(int(renders.Add(1)))))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache.mutex.Lock()
+			for _, entry := range cache.entries {
+				entry.created = entry.created.Add(-tt.age)
+			}
+			cache.mutex.Unlock()
+
+			w := httptest.NewRecorder()
+			cache.Serve(w, httptest.NewRequest(http.MethodGet, "/page", nil), policy, render)
+			if got := w.Header().Get("X-Cache"); got != tt.cache {
+				t.Errorf("X-Cache = %q, want %q", got, tt.cache)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.body)
+			}
+
+			if tt.cache == "STALE" {
+				waitFor(t, func() bool { return cache.Stats().Revalidated == 1 })
+			}
+		})
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.StaleHits != 1 || stats.Misses != 2 || stats.Revalidated != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestPageCacheCollapsesRenders(t *testing.T) {
+	cache := NewPageCache(0, testLogger())
+	policy := PageCachePolicy{TTL: time.Minute}
+
+	release := make(chan struct{})
+	var renders atomic.Int32
+	render := func(w http.ResponseWriter, r *http.Request) {
+		renders.Add(1)
+		<-release
+		w.Write([]byte("page"))
+	}
+
+	const requests = 8
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			cache.Serve(w, httptest.NewRequest(http.MethodGet, "/page", nil), policy, render)
+			if w.Body.String() != "page" {
+				t.Errorf("body = %q, want page", w.Body.String())
+			}
+		}()
+	}
+
+	waitFor(t, func() bool { return cache.Stats().Collapsed == requests-1 })
+	close(release)
+	wg.Wait()
+
+	if renders.Load() != 1 {
+		t.Errorf("rendered %d times for %d concurrent requests, want 1", renders.Load(), requests)
+	}
+}
+
+func TestPageCacheEvictionAndPurge(t *testing.T) {
+	policy := PageCachePolicy{TTL: time.Minute}
+	render := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}
+
+	tests := []struct {
+		name    string
+		serve   []string
+		purge   string
+		purged  int
+		entries int
+		cached  []string
+	}{
+		{"evicts oldest", []string{"/a", "/b", "/c"}, "", 0, 2, []string{"/b", "/c"}},
+		{"purges every query of a path", []string{"/a", "/a?page=2", "/b"}, "/a", 2, 1, []string{"/b"}},
+		{"purge normalizes", []string{"/a/"}, "/a", 1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewPageCache(len(tt.serve)-1, testLogger())
+			if tt.purge != "" {
+				cache.MaxEntries = 0
+			}
+			for i, target := range tt.serve {
+				cache.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil), policy, render)
+				cache.mutex.Lock()
+				cache.entries[policy.cacheKey(httptest.NewRequest(http.MethodGet, target, nil))].created = time.Now().Add(time.Duration(i-len(tt.serve)) * time.Second)
+				cache.mutex.Unlock()
+			}
+
+			if tt.purge != "" {
+				if purged := cache.Purge(tt.purge); purged != tt.purged {
+					t.Errorf("purged %d entries, want %d", purged, tt.purged)
+				}
+			}
+			if entries := cache.Stats().Entries; entries != tt.entries {
+				t.Errorf("entries = %d, want %d", entries, tt.entries)
+			}
+			for _, target := range tt.cached {
+				w := httptest.NewRecorder()
+				cache.Serve(w, httptest.NewRequest(http.MethodGet, target, nil), policy, render)
+				if got := w.Header().Get("X-Cache"); got != "HIT" {
+					t.Errorf("%s: X-Cache = %q, want HIT", target, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPageCachePurgeDuringRender(t *testing.T) {
+	cache := NewPageCache(0, testLogger())
+	policy := PageCachePolicy{TTL: time.Minute}
+
+	render := func(w http.ResponseWriter, r *http.Request) {
+		cache.Purge("/page")
+		w.Write([]byte("old"))
+	}
+	cache.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/page", nil), policy, render)
+
+	if entries := cache.Stats().Entries; entries != 0 {
+		t.Errorf("entries = %d, want a render that overlapped a purge to be dropped", entries)
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
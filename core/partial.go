@@ -0,0 +1,64 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+)
+
+const defaultPartialBlock = "content"
+
+func IsPartialRequest(req *http.Request) bool {
+	if req.Header.Get("HX-Request") == "true" {
+		return req.Header.Get("HX-Boosted") != "true" &&
+			req.Header.Get("HX-History-Restore-Request") != "true"
+	}
+
+	return strings.EqualFold(req.Header.Get("X-Requested-With"), "XMLHttpRequest")
+}
+
+func partialBlock(req *http.Request) string {
+	if AppConfig.PartialBlockHeader != "" {
+		if block := strings.TrimSpace(req.Header.Get(AppConfig.PartialBlockHeader)); block != "" {
+			return block
+		}
+	}
+
+	if AppConfig.PartialBlockParam != "" {
+		if block := strings.TrimSpace(req.URL.Query().Get(AppConfig.PartialBlockParam)); block != "" {
+			return block
+		}
+	}
+
+	if IsPartialRequest(req) {
+		return defaultPartialBlock
+	}
+
+	return ""
+}
+
+func partialVaryHeaders() []string {
+	headers := []string{"HX-Request", "HX-Boosted", "HX-History-Restore-Request", "X-Requested-With"}
+	if AppConfig.PartialBlockHeader != "" {
+		headers = append(headers, AppConfig.PartialBlockHeader)
+	}
+	return headers
+}
+
+func addVary(header http.Header, names ...string) {
+	present := make(map[string]bool)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			present[http.CanonicalHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+	if present["*"] {
+		return
+	}
+
+	for _, name := range names {
+		if key := http.CanonicalHeaderKey(name); !present[key] {
+			header.Add("Vary", name)
+			present[key] = true
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAddVary(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		add      []string
+		want     []string
+	}{
+		{"empty", nil, []string{"HX-Request"}, []string{"HX-Request"}},
+		{"keeps existing", []string{"Origin"}, []string{"HX-Request"}, []string{"Origin", "HX-Request"}},
+		{"skips duplicates", []string{"Accept-Encoding, hx-request"}, []string{"HX-Request", "HX-Boosted"}, []string{"Accept-Encoding, hx-request", "HX-Boosted"}},
+		{"star", []string{"*"}, []string{"HX-Request"}, []string{"*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for _, value := range tt.existing {
+				header.Add("Vary", value)
+			}
+			addVary(header, tt.add...)
+			if got := header.Values("Vary"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Vary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+type routeParamsKey struct{}
+
+type routerKey struct{}
+
+type Policy func(ctx *PolicyContext) bool
+
+type PolicyContext struct {
+	User    *User
+	Action  string
+	Params  map[string]string
+	Args    []interface{}
+	Request *http.Request
+}
+
+type AccessRule struct {
+	Roles       []string
+	Permissions []string
+	Policy      string
+}
+
+var (
+	policiesMutex sync.RWMutex
+	policies      = make(map[string]Policy)
+)
+
+func RegisterPolicy(action string, policy Policy) {
+	policiesMutex.Lock()
+	defer policiesMutex.Unlock()
+	policies[action] = policy
+}
+
+func lookupPolicy(action string) (Policy, bool) {
+	policiesMutex.RLock()
+	defer policiesMutex.RUnlock()
+	policy, ok := policies[action]
+	return policy, ok
+}
+
+func Can(r *http.Request, action string, args ...interface{}) bool {
+	return can(&PolicyContext{
+		User:    GetUser(r),
+		Action:  action,
+		Params:  RouteParams(r),
+		Args:    args,
+		Request: r,
+	})
+}
+
+func can(ctx *PolicyContext) bool {
+	if policy, ok := lookupPolicy(ctx.Action); ok {
+		return policy(ctx)
+	}
+	return ctx.User.Can(ctx.Action)
+}
+
+func (u *User) Can(permission string) bool {
+	if u == nil {
+		return false
+	}
+	if containsString(u.Permissions, "*") || containsString(u.Permissions, permission) {
+		return true
+	}
+	for _, role := range u.Roles {
+		granted := AppConfig.RolePermissions[role]
+		if containsString(granted, "*") || containsString(granted, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule AccessRule) Empty() bool {
+	return len(rule.Roles) == 0 && len(rule.Permissions) == 0 && rule.Policy == ""
+}
+
+func (rule AccessRule) allows(ctx *PolicyContext) bool {
+	if rule.Empty() {
+		return true
+	}
+	if ctx.User == nil {
+		return false
+	}
+
+	if len(rule.Roles) > 0 {
+		allowed := false
+		for _, role := range rule.Roles {
+			if ctx.User.HasRole(role) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for _, permission := range rule.Permissions {
+		if !ctx.User.Can(permission) {
+			return false
+		}
+	}
+
+	if rule.Policy != "" {
+		policy, ok := lookupPolicy(rule.Policy)
+		if !ok {
+			return false
+		}
+		return policy(&PolicyContext{
+			User:    ctx.User,
+			Action:  rule.Policy,
+			Params:  ctx.Params,
+			Request: ctx.Request,
+		})
+	}
+	return true
+}
+
+func Authorize(rule AccessRule) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !authorizeRequest(w, r, RouteParams(r), rule) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireRole(roles ...string) MiddlewareFunc {
+	return Authorize(AccessRule{Roles: roles})
+}
+
+func RequirePermission(permissions ...string) MiddlewareFunc {
+	return Authorize(AccessRule{Permissions: permissions})
+}
+
+func RequirePolicy(action string) MiddlewareFunc {
+	return Authorize(AccessRule{Policy: action})
+}
+
+func authorizeRequest(w http.ResponseWriter, r *http.Request, params map[string]string, rules ...AccessRule) bool {
+	ctx := &PolicyContext{
+		User:    GetUser(r),
+		Params:  params,
+		Request: r,
+	}
+
+	for _, rule := range rules {
+		if rule.allows(ctx) {
+			continue
+		}
+
+		isAPI := strings.HasPrefix(r.URL.Path, "/api")
+		switch {
+		case ctx.User == nil && isAPI:
+			RenderError(w, "Authentication required", http.StatusUnauthorized)
+		case ctx.User == nil && oidcIssuer() != "" && r.Method == http.MethodGet:
+			http.Redirect(w, r, AppConfig.OIDCLoginPath+"?return_to="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		case isAPI:
+			RenderError(w, "Forbidden", http.StatusForbidden)
+		default:
+			if router, ok := r.Context().Value(routerKey{}).(*Router); ok {
+				router.serveErrorPage(w, r, http.StatusForbidden)
+			} else {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func accessRulesGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rules := prefixAccessRules(r.URL.Path); len(rules) > 0 && !authorizeRequest(w, r, RouteParams(r), rules...) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func prefixAccessRules(requestPath string) []AccessRule {
+	requestPath = normalizePath(requestPath)
+	if isLoginRoute(requestPath) {
+		return nil
+	}
+
+	var rules []AccessRule
+	for prefix, rule := range AppConfig.AccessRules {
+		prefix = normalizePath(prefix)
+		if prefix == "/" || requestPath == prefix || strings.HasPrefix(requestPath, prefix+"/") {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func isLoginRoute(requestPath string) bool {
+	if mockOIDCEnabled() && strings.HasPrefix(requestPath, mockOIDCPath+"/") {
+		return true
+	}
+	return oidcIssuer() != "" && (requestPath == AppConfig.OIDCLoginPath || requestPath == AppConfig.OIDCCallbackPath)
+}
+
+func (r *Router) pageAccessRules(route string) []AccessRule {
+	var rules []AccessRule
+	meta := r.Marley.PageMeta[route]
+	rule := AccessRule{
+		Roles:       splitList(meta["roles"]),
+		Permissions: splitList(meta["permissions"]),
+		Policy:      strings.TrimSpace(meta["policy"]),
+	}
+	if !rule.Empty() {
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func withRouteParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeParamsKey{}, params))
+}
+
+func RouteParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(routeParamsKey{}).(map[string]string)
+	return params
+}
+
+func templateCan(ctx *RouteContext, action string, args ...interface{}) bool {
+	if ctx == nil {
+		return false
+	}
+
+	var req *http.Request
+	if ctx.Request != nil {
+		req = ctx.Request.req
+	}
+	return can(&PolicyContext{
+		User:    ctx.User,
+		Action:  action,
+		Params:  ctx.Params,
+		Args:    args,
+		Request: req,
+	})
+}
@@ -0,0 +1,225 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type precompressedEncoding struct {
+	Name string
+	Ext  string
+}
+
+var precompressedEncodings = []precompressedEncoding{
+	{Name: "zstd", Ext: ".zst"},
+	{Name: "gzip", Ext: ".gz"},
+}
+
+var compressibleExts = map[string]bool{
+	".css":  true,
+	".js":   true,
+	".mjs":  true,
+	".map":  true,
+	".json": true,
+	".svg":  true,
+	".html": true,
+	".xml":  true,
+	".txt":  true,
+	".ico":  true,
+	".wasm": true,
+}
+
+func isCompressible(name string) bool {
+	return compressibleExts[strings.ToLower(path.Ext(name))]
+}
+
+func isPrecompressedFile(name string) bool {
+	ext := path.Ext(name)
+	for _, encoding := range precompressedEncodings {
+		if ext == encoding.Ext {
+			return true
+		}
+	}
+	return false
+}
+
+func compressContent(encoding string, content []byte) ([]byte, error) {
+	switch encoding {
+	case "gzip":
+		var buf bytes.Buffer
+		gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := gz.Write(content); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "zstd":
+		encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return nil, err
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(content, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", encoding)
+}
+
+func compressAll(content []byte) (map[string][]byte, error) {
+	encoded := make(map[string][]byte)
+	for _, encoding := range precompressedEncodings {
+		compressed, err := compressContent(encoding.Name, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s compress: %w", encoding.Name, err)
+		}
+		if len(compressed) < len(content) {
+			encoded[encoding.Name] = compressed
+		}
+	}
+	return encoded, nil
+}
+
+func PrecompressDir(dir string) (int, error) {
+	count := 0
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isPrecompressedFile(p) || !isCompressible(p) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() < int64(AppConfig.PrecompressMinSize) {
+			return nil
+		}
+
+		var content []byte
+		for _, encoding := range precompressedEncodings {
+			target := p + encoding.Ext
+			if targetInfo, err := os.Stat(target); err == nil && !targetInfo.ModTime().Before(info.ModTime()) {
+				continue
+			}
+
+			if content == nil {
+				if content, err = os.ReadFile(p); err != nil {
+					return fmt.Errorf("failed to read %s: %w", p, err)
+				}
+			}
+
+			compressed, err := compressContent(encoding.Name, content)
+			if err != nil {
+				return fmt.Errorf("failed to %s compress %s: %w", encoding.Name, p, err)
+			}
+			if len(compressed) >= len(content) {
+				continue
+			}
+
+			if err := os.WriteFile(target, compressed, 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", target, err)
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func negotiateEncoding(acceptEncoding string, available []string) string {
+	if acceptEncoding == "" || len(available) == 0 {
+		return ""
+	}
+
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					weight = q
+				}
+			}
+		}
+		weights[name] = weight
+	}
+
+	best, bestWeight := "", 0.0
+	for _, encoding := range precompressedEncodings {
+		if !containsString(available, encoding.Name) {
+			continue
+		}
+
+		weight, ok := weights[encoding.Name]
+		if !ok {
+			weight, ok = weights["*"]
+		}
+		if ok && weight > bestWeight {
+			best, bestWeight = encoding.Name, weight
+		}
+	}
+
+	return best
+}
+
+func precompressedSibling(w http.ResponseWriter, req *http.Request, staticFS fs.FS, name string, info fs.FileInfo) (string, string) {
+	if !AppConfig.ServePrecompressed || isPrecompressedFile(name) {
+		return name, ""
+	}
+
+	var available []string
+	for _, encoding := range precompressedEncodings {
+		sibling, err := fs.Stat(staticFS, name+encoding.Ext)
+		if err == nil && !sibling.IsDir() && !sibling.ModTime().Before(info.ModTime()) {
+			available = append(available, encoding.Name)
+		}
+	}
+	if len(available) == 0 {
+		return name, ""
+	}
+
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), available)
+	for _, candidate := range precompressedEncodings {
+		if candidate.Name == encoding {
+			return name + candidate.Ext, encoding
+		}
+	}
+	return name, ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientInfoKey struct{}
+
+type clientInfo struct {
+	IP     string
+	Scheme string
+	Host   string
+}
+
+type forwardedHop struct {
+	ip    net.IP
+	proto string
+	host  string
+}
+
+type trustedProxies []*net.IPNet
+
+func TrustedProxyMiddleware(proxies []string, logger *AppLogger) MiddlewareFunc {
+	if proxies == nil {
+		proxies = AppConfig.TrustedProxies
+	}
+	trusted, err := parseTrustedProxies(proxies)
+	if err != nil {
+		logger.ErrorLog.Printf("Failed to load trusted proxies, forwarding headers will be ignored: %v", err)
+	} else if len(trusted) == 0 {
+		logger.WarnLog.Printf("No trusted proxies configured: forwarding headers will be ignored")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := trusted.resolve(r)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientInfoKey{}, info)))
+		})
+	}
+}
+
+func validateTrustedProxies() error {
+	_, err := parseTrustedProxies(AppConfig.TrustedProxies)
+	return err
+}
+
+func ClientIP(r *http.Request) string {
+	return requestClientInfo(r).IP
+}
+
+func RequestScheme(r *http.Request) string {
+	return requestClientInfo(r).Scheme
+}
+
+func RequestHost(r *http.Request) string {
+	return requestClientInfo(r).Host
+}
+
+func requestClientInfo(r *http.Request) clientInfo {
+	if info, ok := r.Context().Value(clientInfoKey{}).(clientInfo); ok {
+		return info
+	}
+	return directClientInfo(r)
+}
+
+func directClientInfo(r *http.Request) clientInfo {
+	info := clientInfo{IP: r.RemoteAddr, Scheme: "http", Host: r.Host}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		info.IP = host
+	}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+	return info
+}
+
+func parseTrustedProxies(entries []string) (trustedProxies, error) {
+	var trusted trustedProxies
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		trusted = append(trusted, network)
+	}
+	return trusted, nil
+}
+
+func (p trustedProxies) contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p trustedProxies) resolve(r *http.Request) clientInfo {
+	info := directClientInfo(r)
+	peer := net.ParseIP(info.IP)
+	if peer == nil || !p.contains(peer) {
+		return info
+	}
+
+	hops := forwardedHops(r, peer)
+	selected := -1
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].ip == nil {
+			break
+		}
+		selected = i
+		if !p.contains(hops[i].ip) {
+			break
+		}
+	}
+	if selected == -1 {
+		return info
+	}
+
+	hop := hops[selected]
+	info.IP = hop.ip.String()
+	if hop.proto == "http" || hop.proto == "https" {
+		info.Scheme = hop.proto
+	}
+	if hop.host != "" && !strings.ContainsAny(hop.host, "/\\@ \t") {
+		info.Host = hop.host
+	}
+	return info
+}
+
+func forwardedHops(r *http.Request, peer net.IP) []forwardedHop {
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		var hops []forwardedHop
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				value = strings.Trim(value, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					hop.ip = parseHopIP(value)
+				case "proto":
+					hop.proto = strings.ToLower(value)
+				case "host":
+					hop.host = value
+				}
+			}
+			hops = append(hops, hop)
+		}
+		return hops
+	}
+
+	proto := strings.ToLower(lastListValue(r.Header.Values("X-Forwarded-Proto")))
+	host := lastListValue(r.Header.Values("X-Forwarded-Host"))
+
+	var addresses []string
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		addresses = strings.Split(strings.Join(values, ","), ",")
+	} else if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		addresses = []string{realIP}
+	}
+	if len(addresses) == 0 {
+		return []forwardedHop{{ip: peer, proto: proto, host: host}}
+	}
+
+	hops := make([]forwardedHop, 0, len(addresses))
+	for _, address := range addresses {
+		hops = append(hops, forwardedHop{ip: parseHopIP(address), proto: proto, host: host})
+	}
+	return hops
+}
+
+func parseHopIP(value string) net.IP {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if strings.HasPrefix(value, "[") {
+		if end := strings.Index(value, "]"); end != -1 {
+			value = value[1:end]
+		}
+	} else if strings.Count(value, ":") == 1 {
+		value, _, _ = strings.Cut(value, ":")
+	}
+	return net.ParseIP(value)
+}
+
+func lastListValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	list := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(list[len(list)-1])
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxiesResolve(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10", "::1"})
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		peer    string
+		tls     bool
+		headers map[string][]string
+		want    clientInfo
+	}{
+		{
+			name: "direct client",
+			peer: "203.0.113.5:4000",
+			want: clientInfo{IP: "203.0.113.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "untrusted peer headers ignored",
+			peer:    "203.0.113.5:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"https"}},
+			want:    clientInfo{IP: "203.0.113.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "direct TLS",
+			peer:    "203.0.113.5:4000",
+			tls:     true,
+			headers: map[string][]string{"X-Forwarded-Proto": {"http"}},
+			want:    clientInfo{IP: "203.0.113.5", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name: "x-forwarded-for",
+			peer: "10.0.0.2:4000",
+			headers: map[string][]string{
+				"X-Forwarded-For":   {"198.51.100.1"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"www.example.com"},
+			},
+			want: clientInfo{IP: "198.51.100.1", Scheme: "https", Host: "www.example.com"},
+		},
+		{
+			name:    "spoofed left entries skipped",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1, 10.0.0.7"}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "multiple header lines",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.1"}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "all hops trusted",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.9, 10.0.0.7"}},
+			want:    clientInfo{IP: "10.0.0.9", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "garbage hop stops the walk",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, unknown"}},
+			want:    clientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "x-real-ip",
+			peer:    "192.168.1.10:4000",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.1"}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "proto and host without for",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"a.example.com, b.example.com"}},
+			want:    clientInfo{IP: "10.0.0.2", Scheme: "https", Host: "b.example.com"},
+		},
+		{
+			name:    "invalid proto and host ignored",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}, "X-Forwarded-Proto": {"javascript"}, "X-Forwarded-Host": {"evil.com/path"}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "forwarded",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"Forwarded": {`for=198.51.100.1;proto=https;host=www.example.com`}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "https", Host: "www.example.com"},
+		},
+		{
+			name:    "forwarded chain with ipv6 and port",
+			peer:    "[::1]:4000",
+			headers: map[string][]string{"Forwarded": {`for=1.2.3.4, for="[2001:db8::1]:4711";proto=https, for=10.0.0.3`}},
+			want:    clientInfo{IP: "2001:db8::1", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name: "forwarded wins over x-forwarded-for",
+			peer: "10.0.0.2:4000",
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.1"},
+				"X-Forwarded-For": {"198.51.100.99"},
+			},
+			want: clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:    "forwarded obfuscated identifier",
+			peer:    "10.0.0.2:4000",
+			headers: map[string][]string{"Forwarded": {"for=_hidden, for=198.51.100.1"}},
+			want:    clientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tt.peer
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for name, values := range tt.headers {
+				for _, value := range values {
+					req.Header.Add(name, value)
+				}
+			}
+
+			if got := trusted.resolve(req); got != tt.want {
+				t.Errorf("resolve = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		entry string
+		valid bool
+	}{
+		{"10.0.0.0/8", true},
+		{"192.168.1.10", true},
+		{"::1", true},
+		{"2001:db8::/32", true},
+		{" 10.1.2.3 ", true},
+		{"", true},
+		{"localhost", false},
+		{"10.0.0.0/33", false},
+		{"10.0.0.256", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			if _, err := parseTrustedProxies([]string{tt.entry}); (err == nil) != tt.valid {
+				t.Errorf("parseTrustedProxies(%q) = %v, want valid %v", tt.entry, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTrustedProxyMiddlewareInvalidList(t *testing.T) {
+	var got clientInfo
+	handler := TrustedProxyMiddleware([]string{"not-an-ip"}, testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = requestClientInfo(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	req.RemoteAddr = "10.0.0.2:4000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.IP != "10.0.0.2" {
+		t.Errorf("client IP = %q with an invalid proxy list, want the peer address", got.IP)
+	}
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+func (r *Router) publicFile(requestPath string) (string, bool) {
+	if AppConfig.PublicDir == "" || requestPath == "/" {
+		return "", false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+requestPath), "/")
+	if isBlockedStaticPath(name) {
+		return "", false
+	}
+
+	publicFS, err := AppConfig.SubFS(AppConfig.PublicDir)
+	if err != nil {
+		return "", false
+	}
+
+	info, err := fs.Stat(publicFS, name)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return name, true
+}
+
+func (r *Router) servePublicFile(w http.ResponseWriter, req *http.Request, name string) {
+	publicFS, err := AppConfig.SubFS(AppConfig.PublicDir)
+	if err != nil {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	r.serveStaticFile(w, req, publicFS, StaticMount{Prefix: "/", Dir: AppConfig.PublicDir}, name)
+}
+
+func PublicFiles() ([]string, error) {
+	if AppConfig.PublicDir == "" {
+		return nil, nil
+	}
+
+	publicFS, err := AppConfig.SubFS(AppConfig.PublicDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open public directory: %w", err)
+	}
+
+	var files []string
+	err = fs.WalkDir(publicFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isBlockedStaticPath(p) {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return files, nil
+}
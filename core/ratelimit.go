@@ -0,0 +1,316 @@
+package core
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+	Burst    int
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type RateLimitStore interface {
+	Take(key string, limit RateLimit) (RateLimitResult, error)
+}
+
+type RateLimitKeyFunc func(r *http.Request) string
+
+type RateLimiter struct {
+	Limit  RateLimit
+	Routes map[string]RateLimit
+	Key    RateLimitKeyFunc
+	Store  RateLimitStore
+}
+
+type MemoryRateLimitStore struct {
+	mutex     sync.Mutex
+	maxKeys   int
+	buckets   map[string]*list.Element
+	recent    *list.List
+	lastSweep time.Time
+}
+
+type rateLimitBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+func (l RateLimit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+func (l RateLimit) perSecond() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+func (l RateLimit) String() string {
+	policy := fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Window.Seconds())))
+	if l.Burst > 0 && l.Burst != l.Requests {
+		policy += fmt.Sprintf(";burst=%d", l.Burst)
+	}
+	return policy
+}
+
+func NewMemoryRateLimitStore(maxKeys int) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for element := s.recent.Front(); element != nil; {
+			next := element.Next()
+			if bucket := element.Value.(*rateLimitBucket); now.After(bucket.full) {
+				s.recent.Remove(element)
+				delete(s.buckets, bucket.key)
+			}
+			element = next
+		}
+		s.lastSweep = now
+	}
+
+	capacity, rate := limit.capacity(), limit.perSecond()
+
+	var bucket *rateLimitBucket
+	if element, ok := s.buckets[key]; ok {
+		s.recent.MoveToFront(element)
+		bucket = element.Value.(*rateLimitBucket)
+		bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	} else {
+		bucket = &rateLimitBucket{key: key, tokens: capacity}
+		s.buckets[key] = s.recent.PushFront(bucket)
+		for s.maxKeys > 0 && s.recent.Len() > s.maxKeys {
+			oldest := s.recent.Back()
+			s.recent.Remove(oldest)
+			delete(s.buckets, oldest.Value.(*rateLimitBucket).key)
+		}
+	}
+	bucket.updated = now
+
+	result := RateLimitResult{Allowed: bucket.tokens >= 1}
+	if result.Allowed {
+		bucket.tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((capacity - bucket.tokens) / rate * float64(time.Second))
+	bucket.full = now.Add(result.Reset)
+	return result, nil
+}
+
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		Limit:  limit,
+		Routes: make(map[string]RateLimit),
+		Key:    RateLimitByIP,
+		Store:  NewMemoryRateLimitStore(AppConfig.RateLimitMaxKeys),
+	}
+}
+
+func defaultRateLimiter(logger *AppLogger) *RateLimiter {
+	key, err := rateLimitKeyFunc(AppConfig.RateLimitKey)
+	if err != nil {
+		logger.ErrorLog.Printf("Invalid rate limit key, limiting by IP: %v", err)
+		key = RateLimitByIP
+	}
+	if AppConfig.RateLimitKey == "api_key" {
+		if _, err := defaultCredentialStore(logger); err != nil {
+			logger.ErrorLog.Printf("Failed to load credentials, rate limiting API keys by IP: %v", err)
+		}
+	}
+
+	limiter := NewRateLimiter(RateLimit{
+		Requests: AppConfig.RateLimit,
+		Window:   AppConfig.RateLimitWindow,
+		Burst:    AppConfig.RateLimitBurst,
+	})
+	limiter.Key = key
+	for prefix, limit := range AppConfig.RateLimitRoutes {
+		limiter.Routes[normalizePath(prefix)] = limit
+	}
+	if AppConfig.RateLimitStore != nil {
+		limiter.Store = AppConfig.RateLimitStore
+	}
+	return limiter
+}
+
+func validateRateLimitConfig(logger *AppLogger) error {
+	if _, err := rateLimitKeyFunc(AppConfig.RateLimitKey); err != nil {
+		return err
+	}
+
+	limit := RateLimit{Requests: AppConfig.RateLimit, Window: AppConfig.RateLimitWindow, Burst: AppConfig.RateLimitBurst}
+	if err := limit.validate(); err != nil {
+		return err
+	}
+	for prefix, limit := range AppConfig.RateLimitRoutes {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("rate limit for %s: %w", prefix, err)
+		}
+	}
+
+	if AppConfig.EnableRateLimit && AppConfig.RateLimitKey == "api_key" {
+		if _, err := defaultCredentialStore(logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l RateLimit) validate() error {
+	if l.Requests < 0 || l.Burst < 0 {
+		return fmt.Errorf("requests and burst must not be negative")
+	}
+	if l.Requests > 0 && l.Window <= 0 {
+		return fmt.Errorf("window must be positive, got %v", l.Window)
+	}
+	return nil
+}
+
+func rateLimitKeyFunc(name string) (RateLimitKeyFunc, error) {
+	switch name {
+	case "", "ip":
+		return RateLimitByIP, nil
+	case "api_key":
+		return RateLimitByAPIKey, nil
+	case "user":
+		return RateLimitByUser, nil
+	}
+	return nil, fmt.Errorf("unknown rate limit key %q", name)
+}
+
+func RateLimitByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+func RateLimitByAPIKey(r *http.Request) string {
+	if credential := GetCredential(r); credential != nil {
+		return "key:" + credential.ID
+	}
+	if key := requestAPIKey(r); key != "" {
+		if store, err := defaultCredentialStore(nil); err == nil {
+			if credential, ok := store.LookupAPIKey(key); ok {
+				return "key:" + credential.ID
+			}
+		}
+	}
+	if claims := GetClaims(r); claims != nil && claims.Subject() != "" {
+		return "sub:" + claims.Subject()
+	}
+	return RateLimitByIP(r)
+}
+
+func RateLimitByUser(r *http.Request) string {
+	if user := GetUser(r); user != nil && user.ID != "" {
+		return "user:" + user.ID
+	}
+	return RateLimitByIP(r)
+}
+
+func (l *RateLimiter) limitFor(requestPath string) (string, RateLimit) {
+	requestPath = normalizePath(requestPath)
+
+	match, limit := "", l.Limit
+	for prefix, routeLimit := range l.Routes {
+		if prefix != "/" && requestPath != prefix && !strings.HasPrefix(requestPath, prefix+"/") {
+			continue
+		}
+		if match == "" || len(prefix) > len(match) {
+			match, limit = prefix, routeLimit
+		}
+	}
+	return match, limit
+}
+
+func RateLimitMiddleware(requestsPerMinute int) MiddlewareFunc {
+	return RateLimiterMiddleware(NewRateLimiter(RateLimit{Requests: requestsPerMinute, Window: time.Minute}), newAppLogger())
+}
+
+func RateLimiterMiddleware(limiter *RateLimiter, logger *AppLogger) MiddlewareFunc {
+	var needsSession sync.Once
+	byUser := limiter == nil && AppConfig.RateLimitKey == "user"
+	if limiter == nil {
+		limiter = defaultRateLimiter(logger)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if byUser && GetSession(r) == nil {
+				needsSession.Do(func() {
+					logger.WarnLog.Printf("RateLimitKey is \"user\" but no session is loaded before the rate limiter: limiting by IP")
+				})
+			}
+
+			route, limit := limiter.limitFor(r.URL.Path)
+			key := limiter.Key(r)
+			if !limit.enabled() || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if route != "" {
+				key = route + "|" + key
+			}
+
+			result, err := limiter.Store.Take(key, limit)
+			if err != nil {
+				logger.ErrorLog.Printf("Rate limit store failed for %s: %v", r.URL.Path, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(int(limit.capacity())))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", limit.String())
+
+			if !result.Allowed {
+				logger.WarnLog.Printf("Rate limit exceeded for %s on %s %s", key, r.Method, r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				if strings.HasPrefix(r.URL.Path, "/api") {
+					RenderError(w, "Too many requests", http.StatusTooManyRequests)
+				} else {
+					http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	store := NewMemoryRateLimitStore(0)
+	limit := RateLimit{Requests: 10, Window: 100 * time.Millisecond}
+
+	for i := 0; i < 10; i++ {
+		if result, _ := store.Take("ip:1", limit); !result.Allowed {
+			t.Fatalf("request %d denied within the burst", i+1)
+		}
+	}
+
+	result, _ := store.Take("ip:1", limit)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("request over the limit = %+v, want denied", result)
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 10*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want at most one token interval", result.RetryAfter)
+	}
+	if result.Reset <= 0 || result.Reset > limit.Window {
+		t.Errorf("Reset = %v, want within the window", result.Reset)
+	}
+
+	if other, _ := store.Take("ip:2", limit); !other.Allowed {
+		t.Error("other key was limited by the first key's bucket")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if result, _ := store.Take("ip:1", limit); !result.Allowed {
+		t.Error("bucket did not refill after waiting")
+	}
+}
+
+func TestMemoryRateLimitStoreBurst(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   RateLimit
+		allowed int
+	}{
+		{"requests", RateLimit{Requests: 3, Window: time.Hour}, 3},
+		{"burst", RateLimit{Requests: 3, Window: time.Hour, Burst: 5}, 5},
+		{"smaller burst", RateLimit{Requests: 3, Window: time.Hour, Burst: 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRateLimitStore(0)
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				if result, _ := store.Take("key", tt.limit); result.Allowed {
+					allowed++
+				}
+			}
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d requests, want %d", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	limit := RateLimit{Requests: 1, Window: time.Hour}
+	exhausted := func(store *MemoryRateLimitStore, key string) bool {
+		result, _ := store.Take(key, limit)
+		return !result.Allowed
+	}
+
+	store := NewMemoryRateLimitStore(2)
+	store.Take("a", limit)
+	store.Take("b", limit)
+	store.Take("a", limit)
+	store.Take("c", limit)
+
+	if len(store.buckets) != 2 || store.recent.Len() != 2 {
+		t.Fatalf("kept %d buckets, want 2", len(store.buckets))
+	}
+	if !exhausted(store, "a") {
+		t.Error("recently used key a was evicted")
+	}
+	if exhausted(store, "b") {
+		t.Error("least recently used key b was kept")
+	}
+
+	store = NewMemoryRateLimitStore(0)
+	fast := RateLimit{Requests: 1, Window: time.Millisecond}
+	store.Take("idle", fast)
+	time.Sleep(5 * time.Millisecond)
+	store.lastSweep = time.Time{}
+	store.Take("active", limit)
+
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("sweep kept a bucket that had refilled")
+	}
+	if _, ok := store.buckets["active"]; !ok {
+		t.Error("sweep removed the active bucket")
+	}
+}
+
+func TestRateLimiterLimitFor(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Requests: 100, Window: time.Minute})
+	limiter.Routes["/api"] = RateLimit{Requests: 50, Window: time.Minute}
+	limiter.Routes["/api/login"] = RateLimit{Requests: 5, Window: time.Minute}
+
+	tests := []struct {
+		path     string
+		route    string
+		requests int
+	}{
+		{"/", "", 100},
+		{"/apiary", "", 100},
+		{"/api", "/api", 50},
+		{"/api/users", "/api", 50},
+		{"/api/login", "/api/login", 5},
+		{"/api/login/", "/api/login", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			route, limit := limiter.limitFor(tt.path)
+			if route != tt.route || limit.Requests != tt.requests {
+				t.Errorf("limitFor = %q, %d; want %q, %d", route, limit.Requests, tt.route, tt.requests)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware MiddlewareFunc
+	}{
+		{"limiter", RateLimiterMiddleware(NewRateLimiter(RateLimit{Requests: 2, Window: time.Minute}), testLogger())},
+		{"requests per minute", RateLimitMiddleware(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			var statuses []int
+			var last *httptest.ResponseRecorder
+			for i := 0; i < 3; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+				req.RemoteAddr = "192.0.2.1:1234"
+				last = httptest.NewRecorder()
+				handler.ServeHTTP(last, req)
+				statuses = append(statuses, last.Code)
+			}
+
+			if statuses[0] != http.StatusNoContent || statuses[1] != http.StatusNoContent || statuses[2] != http.StatusTooManyRequests {
+				t.Fatalf("statuses = %v, want [204 204 429]", statuses)
+			}
+			if got := last.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+				t.Errorf("RateLimit-Policy = %q, want 2;w=60", got)
+			}
+			if got := last.Header().Get("Retry-After"); got != "30" {
+				t.Errorf("Retry-After = %q, want 30", got)
+			}
+		})
+	}
+}
+
+func TestValidateRateLimitConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		limit  int
+		window time.Duration
+		routes map[string]RateLimit
+		valid  bool
+	}{
+		{"defaults", "ip", 100, time.Minute, nil, true},
+		{"user key", "user", 100, time.Minute, nil, true},
+		{"unknown key", "cookie", 100, time.Minute, nil, false},
+		{"negative limit", "ip", -1, time.Minute, nil, false},
+		{"no window", "ip", 100, 0, nil, false},
+		{"disabled without window", "ip", 0, 0, nil, true},
+		{"bad route limit", "ip", 100, time.Minute, map[string]RateLimit{"/api": {Requests: 5}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t)
+			AppConfig.RateLimitKey = tt.key
+			AppConfig.RateLimit = tt.limit
+			AppConfig.RateLimitWindow = tt.window
+			AppConfig.RateLimitRoutes = tt.routes
+
+			if err := validateRateLimitConfig(testLogger()); (err == nil) != tt.valid {
+				t.Errorf("validateRateLimitConfig() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
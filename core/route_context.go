@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type localsKey struct{}
+
+type RequestView struct {
+	Method     string
+	Path       string
+	Host       string
+	Scheme     string
+	RemoteAddr string
+	ClientIP   string
+	header     http.Header
+	cookies    []*http.Cookie
+	req        *http.Request
+}
+
+func newRouteContext(req *http.Request, params map[string]string) *RouteContext {
+	currentURL := *req.URL
+
+	locals := make(map[string]interface{})
+	for key, value := range Locals(req) {
+		locals[key] = value
+	}
+
+	return &RouteContext{
+		Params: params,
+		Config: &AppConfig,
+		Request: &RequestView{
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Host:       RequestHost(req),
+			Scheme:     RequestScheme(req),
+			RemoteAddr: req.RemoteAddr,
+			ClientIP:   ClientIP(req),
+			header:     req.Header.Clone(),
+			cookies:    req.Cookies(),
+			req:        req,
+		},
+		Query:   req.URL.Query(),
+		Locals:  locals,
+		URL:     &currentURL,
+		Form:    url.Values{},
+		Errors:  make(map[string]string),
+		Session: GetSession(req),
+		User:    GetUser(req),
+	}
+}
+
+func (ctx *RouteContext) Local(key string) interface{} {
+	return ctx.Locals[key]
+}
+
+func (ctx *RouteContext) IsActive(p string) bool {
+	if ctx.URL == nil {
+		return false
+	}
+
+	current := normalizePath(ctx.URL.Path)
+	p = normalizePath(p)
+	if p == "/" {
+		return current == "/"
+	}
+	return current == p || strings.HasPrefix(current, p+"/")
+}
+
+func (rv *RequestView) Header(name string) string {
+	return rv.header.Get(name)
+}
+
+func (rv *RequestView) Cookie(name string) string {
+	for _, cookie := range rv.cookies {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+func SetLocal(r *http.Request, key string, value interface{}) *http.Request {
+	locals := make(map[string]interface{})
+	for k, v := range Locals(r) {
+		locals[k] = v
+	}
+	locals[key] = value
+
+	return r.WithContext(context.WithValue(r.Context(), localsKey{}, locals))
+}
+
+func GetLocal(r *http.Request, key string) interface{} {
+	return Locals(r)[key]
+}
+
+func Locals(r *http.Request) map[string]interface{} {
+	locals, _ := r.Context().Value(localsKey{}).(map[string]interface{})
+	return locals
+}
+
+func LocalMiddleware(key string, value interface{}) MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, SetLocal(r, key, value))
+		})
+	}
+}
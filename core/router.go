@@ -1,9 +1,14 @@
 package core
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
-	"os"
+	"net/url"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -30,11 +35,26 @@ type Router struct {
 	StaticDir        string
 	Logger           *AppLogger
 	GlobalMiddleware *MiddlewareChain
+	ParamsProviders  map[string]ParamsProvider
+	Actions          map[string]PageAction
+	PageCache        *PageCache
+	OIDC             *OIDCClient
+	guards           *MiddlewareChain
+	seo              *seoCache
 }
 
 type RouteContext struct {
-	Params map[string]string
-	Config *Config
+	Params  map[string]string
+	Config  *Config
+	Request *RequestView
+	Query   url.Values
+	Locals  map[string]interface{}
+	URL     *url.URL
+	Form    url.Values
+	Errors  map[string]string
+	Flash   string
+	Session *Session
+	User    *User
 }
 
 type APIHandler interface {
@@ -56,6 +76,14 @@ func (ctx *APIContext) Error(message string, statusCode int) {
 	RenderError(ctx.Writer, message, statusCode)
 }
 
+func (ctx *APIContext) Session() *Session {
+	return GetSession(ctx.Request)
+}
+
+func (ctx *APIContext) Claims() Claims {
+	return GetClaims(ctx.Request)
+}
+
 func (ctx *APIContext) ParseBody(v interface{}) error {
 	return ParseBody(ctx.Request, v)
 }
@@ -71,6 +99,11 @@ func NewRouter(logger *AppLogger) *Router {
 		StaticDir:        AppConfig.StaticDir,
 		Logger:           logger,
 		GlobalMiddleware: NewMiddlewareChain(),
+		guards:           &MiddlewareChain{middlewares: []MiddlewareFunc{accessRulesGuard}},
+		ParamsProviders:  make(map[string]ParamsProvider),
+		Actions:          make(map[string]PageAction),
+		PageCache:        NewPageCache(AppConfig.PageCacheMaxEntries, logger),
+		seo:              &seoCache{},
 	}
 }
 
@@ -78,6 +111,20 @@ func (r *Router) Use(middleware MiddlewareFunc) {
 	r.GlobalMiddleware.Use(middleware)
 }
 
+func (r *Router) guard(middleware MiddlewareFunc) {
+	r.guards.Use(middleware)
+}
+
+func (r *Router) chain(routeMiddleware *MiddlewareChain, handler http.Handler) http.Handler {
+	if routeMiddleware != nil {
+		handler = routeMiddleware.Then(handler)
+	}
+	if r.guards != nil {
+		handler = r.guards.Then(handler)
+	}
+	return r.GlobalMiddleware.Then(handler)
+}
+
 func (r *Router) AddRoute(path string, handler http.HandlerFunc, middleware ...MiddlewareFunc) {
 	mc := NewMiddlewareChain()
 	for _, m := range middleware {
@@ -140,22 +187,24 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	path := normalizePath(req.URL.Path)
+	req = req.WithContext(context.WithValue(req.Context(), routerKey{}, r))
 
 	if r.GlobalMiddleware == nil {
 		r.GlobalMiddleware = NewMiddlewareChain()
 	}
 
-	if strings.HasPrefix(path, "/static") {
-		for _, route := range r.Routes {
-			if route.IsStatic {
-				if route.Middleware == nil {
-					route.Middleware = NewMiddlewareChain()
-				}
-				handler := r.GlobalMiddleware.Then(http.HandlerFunc(route.Handler))
-				handler.ServeHTTP(w, req)
-				return
-			}
-		}
+	if route, ok := r.matchStaticRoute(req.URL.Path); ok {
+		handler := r.chain(route.Middleware, http.HandlerFunc(route.Handler))
+		handler.ServeHTTP(w, req)
+		return
+	}
+
+	if name, ok := r.publicFile(path); ok {
+		handler := r.chain(nil, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.servePublicFile(w, req, name)
+		}))
+		handler.ServeHTTP(w, req)
+		return
 	}
 
 	if strings.HasPrefix(path, "/api") {
@@ -166,8 +215,8 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 					if route.Middleware == nil {
 						route.Middleware = NewMiddlewareChain()
 					}
-					handler := r.GlobalMiddleware.Then(route.Middleware.Then(http.HandlerFunc(route.Handler)))
-					handler.ServeHTTP(w, req)
+					handler := r.chain(route.Middleware, http.HandlerFunc(route.Handler))
+					handler.ServeHTTP(w, withRouteParams(req, extractParamsFromRequest(path, route.Path)))
 					return
 				}
 			}
@@ -185,7 +234,7 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				if route.Middleware == nil {
 					route.Middleware = NewMiddlewareChain()
 				}
-				handler := r.GlobalMiddleware.Then(route.Middleware.Then(http.HandlerFunc(route.Handler)))
+				handler := r.chain(route.Middleware, http.HandlerFunc(route.Handler))
 				handler.ServeHTTP(w, req)
 				return
 			}
@@ -198,8 +247,8 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				if route.Middleware == nil {
 					route.Middleware = NewMiddlewareChain()
 				}
-				handler := r.GlobalMiddleware.Then(route.Middleware.Then(http.HandlerFunc(route.Handler)))
-				handler.ServeHTTP(w, req)
+				handler := r.chain(route.Middleware, http.HandlerFunc(route.Handler))
+				handler.ServeHTTP(w, withRouteParams(req, extractParamsFromRequest(path, route.Path)))
 				return
 			}
 		}
@@ -210,18 +259,30 @@ func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 func (r *Router) InitRoutes() error {
+	return r.initRoutes(r.Marley.LoadTemplates)
+}
+
+func (r *Router) ReloadRoutes() error {
+	return r.initRoutes(r.Marley.ReloadTemplates)
+}
+
+func (r *Router) initRoutes(loadTemplates func() error) error {
 	startTime := time.Now()
 	r.Logger.InfoLog.Printf("Initializing routes...")
 
 	r.Routes = []Route{}
 
-	err := r.Marley.LoadTemplates()
+	err := loadTemplates()
 	if err != nil {
 		r.Logger.ErrorLog.Printf("Failed to load templates: %v", err)
 		return fmt.Errorf("failed to load templates: %w", err)
 	}
 
+	r.PageCache.PurgeAll()
+	r.seo.reset()
 	r.AddStaticRoute()
+	r.addSEORoutes()
+	r.addOIDCRoutes()
 
 	routeCount := 0
 	for routePath := range r.Marley.Templates {
@@ -245,6 +306,10 @@ func (r *Router) InitRoutes() error {
 			Middleware: NewMiddlewareChain(),
 		})
 
+		if name, ok := r.publicFile(routePath); ok {
+			r.Logger.WarnLog.Printf("Route %s is shadowed by %s", routePath, path.Join(AppConfig.PublicDir, name))
+		}
+
 		r.Logger.InfoLog.Printf("Route registered: %s (params: %v)", routePath, paramNames)
 		routeCount++
 	}
@@ -263,16 +328,17 @@ func (r *Router) InitRoutes() error {
 }
 
 func (r *Router) loadAPIRoutes() (int, error) {
-	apiBasePath := filepath.Join(AppConfig.AppDir, "api")
+	siteFS := AppConfig.SiteFS()
+	apiBasePath := fsPath(filepath.Join(AppConfig.AppDir, "api"))
 	apiRouteCount := 0
 
-	if _, err := os.Stat(apiBasePath); os.IsNotExist(err) {
+	if _, err := fs.Stat(siteFS, apiBasePath); errors.Is(err, fs.ErrNotExist) {
 		return 0, nil
 	}
 
 	
-	usersPath := filepath.Join(apiBasePath, "users")
-	if _, err := os.Stat(usersPath); err == nil {
+	usersPath := path.Join(apiBasePath, "users")
+	if _, err := fs.Stat(siteFS, usersPath); err == nil {
 		
 		r.API("/api/users", func(ctx *APIContext) {
 			
@@ -311,8 +377,8 @@ func (r *Router) loadAPIRoutes() (int, error) {
 	}
 
 	
-	testPath := filepath.Join(apiBasePath, "test")
-	if _, err := os.Stat(testPath); err == nil {
+	testPath := path.Join(apiBasePath, "test")
+	if _, err := fs.Stat(siteFS, testPath); err == nil {
 		
 		r.API("/api/test", func(ctx *APIContext) {
 			
@@ -501,34 +567,78 @@ func (r *Router) handleTestAPI(ctx *APIContext) {
 }
 
 func (r *Router) AddStaticRoute() {
-	staticHandler := http.StripPrefix("/static/", http.FileServer(http.Dir(r.StaticDir)))
-	r.Routes = append(r.Routes, Route{
-		Path: "/static/",
-		Handler: func(w http.ResponseWriter, req *http.Request) {
-			staticHandler.ServeHTTP(w, req)
-		},
-		IsStatic:   true,
-		Middleware: NewMiddlewareChain(),
-	})
+	for i, mount := range r.StaticMounts() {
+		r.addStaticMount(mount, i == 0)
+	}
+}
 
-	r.Logger.InfoLog.Printf("Static route registered: /static/ → %s", r.StaticDir)
+func (r *Router) serveBundle(w http.ResponseWriter, req *http.Request, name string) bool {
+	if bundle, ok := r.Marley.Bundle(name); ok {
+		content := bundle.Content
+		if AppConfig.ServePrecompressed && len(bundle.Encoded) > 0 {
+			var available []string
+			for encoding := range bundle.Encoded {
+				available = append(available, encoding)
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			if encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), available); encoding != "" {
+				w.Header().Set("Content-Encoding", encoding)
+				content = bundle.Encoded[encoding]
+			}
+		}
+
+		setStaticCacheControl(w, name)
+		http.ServeContent(w, req, name, bundle.ModTime, bytes.NewReader(content))
+		return true
+	}
+
+	if bundle, ok := r.Marley.Bundle(strings.TrimSuffix(name, ".map")); ok && bundle.SourceMap != nil {
+		w.Header().Set("Content-Type", "application/json")
+		setStaticCacheControl(w, name)
+		http.ServeContent(w, req, name, bundle.ModTime, bytes.NewReader(bundle.SourceMap))
+		return true
+	}
+
+	return false
 }
 
 func (r *Router) createTemplateHandler(route string) http.HandlerFunc {
+	policy := r.pageCachePolicy(route)
+	if policy.Enabled() {
+		policy.VaryHeaders = append(append([]string{}, policy.VaryHeaders...), partialVaryHeaders()...)
+	}
+	if policy.Enabled() && (r.Marley.UsesFuncs(route, userTemplateFuncs...) || r.Marley.UsesFields(route, policy.perRequestField)) {
+		r.Logger.WarnLog.Printf("Page cache disabled for %s: the page renders per-user content", route)
+		policy = PageCachePolicy{}
+	}
+	if policy.Enabled() {
+		r.Logger.InfoLog.Printf("Page cache enabled for %s (ttl: %v, stale-while-revalidate: %v)",
+			route, policy.TTL, policy.StaleWhileRevalidate)
+	}
+
+	accessRules := r.pageAccessRules(route)
+
+	render := func(w http.ResponseWriter, req *http.Request) {
+		ctx := newRouteContext(req, extractParamsFromRequest(req.URL.Path, route))
+		ctx.Flash = consumeFlash(w, req)
+		r.renderPage(w, req, route, ctx, http.StatusOK)
+	}
+
 	return func(w http.ResponseWriter, req *http.Request) {
 		startTime := time.Now()
 
-		params := extractParamsFromRequest(req.URL.Path, route)
-		ctx := &RouteContext{
-			Params: params,
-			Config: &AppConfig,
+		if len(accessRules) > 0 && !authorizeRequest(w, req, extractParamsFromRequest(req.URL.Path, route), accessRules...) {
+			return
 		}
 
-		err := r.Marley.RenderTemplate(w, route, ctx)
-		if err != nil {
-			r.Logger.ErrorLog.Printf("Template rendering error for %s: %v", route, err)
-			r.serveErrorPage(w, req, http.StatusInternalServerError)
-			return
+		switch {
+		case req.Method != http.MethodGet && req.Method != http.MethodHead:
+			r.serveAction(w, req, route)
+		case policy.Enabled() && !hasFlash(req):
+			r.PageCache.Serve(w, req, policy, render)
+		default:
+			render(w, req)
 		}
 
 		if AppConfig.LogLevel == "debug" {
@@ -538,27 +648,58 @@ func (r *Router) createTemplateHandler(route string) http.HandlerFunc {
 	}
 }
 
+func (r *Router) renderPage(w http.ResponseWriter, req *http.Request, route string, ctx *RouteContext, status int) {
+	addVary(w.Header(), partialVaryHeaders()...)
+
+	block := partialBlock(req)
+	if block != "" && !r.Marley.HasBlock(route, block) {
+		http.Error(w, fmt.Sprintf("Unknown template block %q", block), http.StatusBadRequest)
+		return
+	}
+
+	buf := newResponseBuffer()
+	buf.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteHeader(status)
+
+	var err error
+	if block != "" {
+		err = r.Marley.RenderBlock(buf, route, block, ctx)
+	} else {
+		err = r.Marley.RenderTemplate(buf, route, ctx)
+	}
+	if err != nil {
+		r.Logger.ErrorLog.Printf("Template rendering error for %s: %v", route, err)
+		r.serveErrorPage(w, req, http.StatusInternalServerError)
+		return
+	}
+
+	buf.writeTo(w, "")
+}
+
 func (r *Router) serveErrorPage(w http.ResponseWriter, req *http.Request, status int) {
 	var errorPage string
 
 	switch status {
 	case http.StatusNotFound:
 		errorPage = "404"
+	case http.StatusForbidden:
+		errorPage = "403"
 	case http.StatusInternalServerError:
 		errorPage = "500"
 	default:
 		errorPage = "error"
 	}
 
-	customErrorPath := filepath.Join(AppConfig.AppDir, errorPage+".html")
-	if _, err := os.Stat(customErrorPath); err == nil {
-		ctx := &RouteContext{
-			Params: map[string]string{
-				"status": fmt.Sprintf("%d", status),
-				"path":   req.URL.Path,
-			},
-			Config: &AppConfig,
-		}
+	customErrorPath := fsPath(filepath.Join(AppConfig.AppDir, errorPage+".html"))
+	if _, err := fs.Stat(AppConfig.SiteFS(), customErrorPath); err != nil && status == http.StatusForbidden {
+		errorPage = "error"
+		customErrorPath = fsPath(filepath.Join(AppConfig.AppDir, errorPage+".html"))
+	}
+	if _, err := fs.Stat(AppConfig.SiteFS(), customErrorPath); err == nil {
+		ctx := newRouteContext(req, map[string]string{
+			"status": fmt.Sprintf("%d", status),
+			"path":   req.URL.Path,
+		})
 
 		if tmpl, exists := r.Marley.Templates["/"+errorPage]; exists {
 			w.WriteHeader(status)
@@ -0,0 +1,332 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const sessionSecretMinLength = 32
+
+type sessionKey struct{}
+
+type Session struct {
+	ID        string                 `json:"id"`
+	Values    map[string]interface{} `json:"values"`
+	Flashes   []string               `json:"flashes,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	ExpiresAt time.Time              `json:"expires_at"`
+	isNew     bool
+	modified  bool
+	destroyed bool
+	previous  *Session
+}
+
+type sessionState struct {
+	req       *http.Request
+	store     SessionStore
+	codec     *sessionCodec
+	logger    *AppLogger
+	once      sync.Once
+	session   *Session
+	committed bool
+}
+
+type sessionWriter struct {
+	http.ResponseWriter
+	state *sessionState
+}
+
+type sessionCodec struct {
+	aead cipher.AEAD
+}
+
+func NewSession(ttl time.Duration) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        newSessionID(),
+		Values:    make(map[string]interface{}),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		isNew:     true,
+	}
+}
+
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+func (s *Session) GetString(key string) string {
+	value, _ := s.Values[key].(string)
+	return value
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key string) {
+	if _, ok := s.Values[key]; ok {
+		delete(s.Values, key)
+		s.modified = true
+	}
+}
+
+func (s *Session) Clear() {
+	s.Values = make(map[string]interface{})
+	s.modified = true
+}
+
+func (s *Session) AddFlash(message string) {
+	s.Flashes = append(s.Flashes, message)
+	s.modified = true
+}
+
+func (s *Session) HasFlashes() bool {
+	return len(s.Flashes) > 0
+}
+
+func (s *Session) PopFlashes() []string {
+	flashes := s.Flashes
+	if len(flashes) > 0 {
+		s.Flashes = nil
+		s.modified = true
+	}
+	return flashes
+}
+
+func (s *Session) Rotate() {
+	if s.previous == nil && !s.isNew {
+		previous := *s
+		s.previous = &previous
+	}
+	s.ID = newSessionID()
+	s.modified = true
+}
+
+func (s *Session) Destroy() {
+	s.destroyed = true
+	s.modified = true
+}
+
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+func (s *Session) expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)
+}
+
+func SessionMiddleware(store SessionStore, logger *AppLogger) MiddlewareFunc {
+	if store == nil {
+		store = AppConfig.SessionStore
+	}
+	if store == nil {
+		store = NewCookieSessionStore()
+	}
+
+	secret := AppConfig.SessionSecret
+	if secret == "" {
+		logger.WarnLog.Printf("SessionSecret is not set, using a random key: sessions will not survive a restart")
+		secret = newSessionID()
+	}
+
+	codec, err := newSessionCodec(secret)
+	if err != nil {
+		logger.ErrorLog.Printf("Failed to create session codec, sessions are disabled: %v", err)
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &sessionState{
+				store:  store,
+				codec:  codec,
+				logger: logger,
+			}
+			r = r.WithContext(context.WithValue(r.Context(), sessionKey{}, state))
+			state.req = r
+
+			sw := &sessionWriter{ResponseWriter: w, state: state}
+			next.ServeHTTP(sw, r)
+			state.commit(w)
+		})
+	}
+}
+
+func GetSession(r *http.Request) *Session {
+	state, ok := r.Context().Value(sessionKey{}).(*sessionState)
+	if !ok {
+		return nil
+	}
+	return state.load()
+}
+
+func (st *sessionState) load() *Session {
+	st.once.Do(func() {
+		if cookie, err := st.req.Cookie(AppConfig.SessionCookieName); err == nil {
+			if token, err := st.codec.decode(AppConfig.SessionCookieName, cookie.Value); err == nil {
+				session, err := st.store.Load(string(token))
+				if err == nil && session != nil && !session.expired(time.Now()) {
+					if session.Values == nil {
+						session.Values = make(map[string]interface{})
+					}
+					st.session = session
+					return
+				}
+				if err != nil && !errors.Is(err, ErrSessionNotFound) {
+					st.logger.WarnLog.Printf("Failed to load session: %v", err)
+				}
+			}
+		}
+		st.session = NewSession(AppConfig.SessionTTL)
+	})
+	return st.session
+}
+
+func (st *sessionState) commit(w http.ResponseWriter) {
+	if st.committed || st.session == nil {
+		return
+	}
+	st.committed = true
+
+	session := st.session
+	if session.previous != nil {
+		if err := st.store.Delete(session.previous); err != nil {
+			st.logger.WarnLog.Printf("Failed to delete rotated session: %v", err)
+		}
+	}
+
+	if session.destroyed {
+		if err := st.store.Delete(session); err != nil {
+			st.logger.WarnLog.Printf("Failed to delete session: %v", err)
+		}
+		http.SetCookie(w, st.cookie("", -1))
+		return
+	}
+
+	ttl := AppConfig.SessionTTL
+	if !session.modified && (session.isNew || time.Until(session.ExpiresAt) > ttl/2) {
+		return
+	}
+	session.ExpiresAt = time.Now().Add(ttl)
+
+	token, err := st.store.Save(session)
+	if err != nil {
+		st.logger.ErrorLog.Printf("Failed to save session: %v", err)
+		return
+	}
+
+	value, err := st.codec.encode(AppConfig.SessionCookieName, []byte(token))
+	if err != nil {
+		st.logger.ErrorLog.Printf("Failed to encode session cookie: %v", err)
+		return
+	}
+	if len(value) > 4096 {
+		st.logger.ErrorLog.Printf("Session cookie is %d bytes, larger than browsers accept", len(value))
+		return
+	}
+
+	http.SetCookie(w, st.cookie(value, int(ttl.Seconds())))
+}
+
+func (st *sessionState) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     AppConfig.SessionCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   AppConfig.SessionCookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+func (sw *sessionWriter) WriteHeader(status int) {
+	sw.state.commit(sw.ResponseWriter)
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *sessionWriter) Write(p []byte) (int, error) {
+	sw.state.commit(sw.ResponseWriter)
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *sessionWriter) Flush() {
+	sw.state.commit(sw.ResponseWriter)
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+func validateSessionConfig() error {
+	if AppConfig.SessionSecret != "" && len(AppConfig.SessionSecret) < sessionSecretMinLength {
+		return fmt.Errorf("SessionSecret must be at least %d bytes", sessionSecretMinLength)
+	}
+	if err := (&http.Cookie{Name: AppConfig.SessionCookieName, Value: "x"}).Valid(); err != nil {
+		return fmt.Errorf("invalid SessionCookieName %q: %w", AppConfig.SessionCookieName, err)
+	}
+	if AppConfig.SessionTTL <= 0 {
+		return fmt.Errorf("SessionTTL must be positive, got %v", AppConfig.SessionTTL)
+	}
+	return nil
+}
+
+func newSessionCodec(secret string) (*sessionCodec, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionCodec{aead: aead}, nil
+}
+
+func (c *sessionCodec) encode(name string, value []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, value, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *sessionCodec) decode(name, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("session cookie is too short")
+	}
+
+	return c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+}
+
+func newSessionID() string {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(fmt.Sprintf("failed to generate session id: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+type SessionStore interface {
+	Load(token string) (*Session, error)
+	Save(session *Session) (string, error)
+	Delete(session *Session) error
+}
+
+type CookieSessionStore struct{}
+
+type MemorySessionStore struct {
+	mutex     sync.Mutex
+	sessions  map[string][]byte
+	expiry    map[string]time.Time
+	lastSweep time.Time
+}
+
+type FileSessionStore struct {
+	Dir string
+}
+
+func NewCookieSessionStore() *CookieSessionStore {
+	return &CookieSessionStore{}
+}
+
+func (s *CookieSessionStore) Load(token string) (*Session, error) {
+	return decodeSession([]byte(token))
+}
+
+func (s *CookieSessionStore) Save(session *Session) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *CookieSessionStore) Delete(session *Session) error {
+	return nil
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string][]byte),
+		expiry:   make(map[string]time.Time),
+	}
+}
+
+func (s *MemorySessionStore) Load(token string) (*Session, error) {
+	s.mutex.Lock()
+	data, ok := s.sessions[token]
+	s.mutex.Unlock()
+
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return decodeSession(data)
+}
+
+func (s *MemorySessionStore) Save(session *Session) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for id, expiresAt := range s.expiry {
+			if now.After(expiresAt) {
+				delete(s.sessions, id)
+				delete(s.expiry, id)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.sessions[session.ID] = data
+	s.expiry[session.ID] = session.ExpiresAt
+	return session.ID, nil
+}
+
+func (s *MemorySessionStore) Delete(session *Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sessions, session.ID)
+	delete(s.expiry, session.ID)
+	return nil
+}
+
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %w", err)
+	}
+	return &FileSessionStore{Dir: dir}, nil
+}
+
+func (s *FileSessionStore) Load(token string) (*Session, error) {
+	file, err := s.path(token)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session: %w", err)
+	}
+
+	session, err := decodeSession(data)
+	if err != nil {
+		return nil, err
+	}
+	if session.expired(time.Now()) {
+		os.Remove(file)
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *FileSessionStore) Save(session *Session) (string, error) {
+	file, err := s.path(session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, ".session-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return session.ID, nil
+}
+
+func (s *FileSessionStore) Delete(session *Session) error {
+	file, err := s.path(session.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (s *FileSessionStore) path(id string) (string, error) {
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return "", fmt.Errorf("invalid session id")
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("invalid session id")
+	}
+	return filepath.Join(s.Dir, id+".json"), nil
+}
+
+func decodeSession(data []byte) (*Session, error) {
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &session, nil
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionCodec(t *testing.T) {
+	codec, err := newSessionCodec("0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("newSessionCodec: %v", err)
+	}
+	other, err := newSessionCodec("fedcba9876543210fedcba9876543210")
+	if err != nil {
+		t.Fatalf("newSessionCodec: %v", err)
+	}
+
+	encoded, err := codec.encode("goa_session", []byte("token"))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	again, _ := codec.encode("goa_session", []byte("token"))
+	if encoded == again {
+		t.Error("encoding the same value twice gave the same ciphertext")
+	}
+
+	tampered := []byte(encoded)
+	if tampered[len(tampered)-1] == 'A' {
+		tampered[len(tampered)-1] = 'B'
+	} else {
+		tampered[len(tampered)-1] = 'A'
+	}
+
+	tests := []struct {
+		name   string
+		codec  *sessionCodec
+		cookie string
+		value  string
+		valid  bool
+	}{
+		{"round trip", codec, "goa_session", encoded, true},
+		{"other cookie name", codec, "other_session", encoded, false},
+		{"other key", other, "goa_session", encoded, false},
+		{"tampered", codec, "goa_session", string(tampered), false},
+		{"truncated", codec, "goa_session", encoded[:10], false},
+		{"not base64", codec, "goa_session", "%%%", false},
+		{"empty", codec, "goa_session", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := tt.codec.decode(tt.cookie, tt.value)
+			if valid := err == nil; valid != tt.valid {
+				t.Fatalf("valid = %v, want %v (err: %v)", valid, tt.valid, err)
+			}
+			if tt.valid && string(decoded) != "token" {
+				t.Errorf("decoded = %q, want %q", decoded, "token")
+			}
+		})
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		expired   bool
+	}{
+		{"no expiry", time.Time{}, false},
+		{"future", now.Add(time.Minute), false},
+		{"past", now.Add(-time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{ExpiresAt: tt.expiresAt}
+			if got := session.expired(now); got != tt.expired {
+				t.Errorf("expired = %v, want %v", got, tt.expired)
+			}
+		})
+	}
+}
+
+func TestSessionMiddlewareExpiry(t *testing.T) {
+	withTestConfig(t)
+	AppConfig.SessionSecret = "0123456789abcdef0123456789abcdef"
+	AppConfig.SessionCookieName = "goa_session"
+	AppConfig.SessionTTL = 50 * time.Millisecond
+
+	handler := SessionMiddleware(NewMemorySessionStore(), testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := GetSession(r)
+		if r.URL.Query().Has("set") {
+			session.Set("name", "alice")
+		}
+		w.Write([]byte(session.GetString("name")))
+	}))
+
+	request := func(target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request("/?set", nil)
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "goa_session" || !cookies[0].HttpOnly {
+		t.Fatalf("cookies after set = %+v, want one HttpOnly goa_session cookie", cookies)
+	}
+	if strings.Contains(cookies[0].Value, "alice") {
+		t.Errorf("cookie value is not encrypted: %q", cookies[0].Value)
+	}
+
+	if body := request("/", cookies[0]).Body.String(); body != "alice" {
+		t.Errorf("value before expiry = %q, want alice", body)
+	}
+	if w := request("/", nil); w.Body.String() != "" || len(w.Result().Cookies()) != 0 {
+		t.Errorf("untouched session without a cookie wrote %q and %d cookies", w.Body.String(), len(w.Result().Cookies()))
+	}
+
+	time.Sleep(2 * AppConfig.SessionTTL)
+	if body := request("/", cookies[0]).Body.String(); body != "" {
+		t.Errorf("value after expiry = %q, want none", body)
+	}
+}
+
+func TestValidateSessionConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		cookie string
+		ttl    time.Duration
+		valid  bool
+	}{
+		{"defaults", "", "goa_session", 24 * time.Hour, true},
+		{"long secret", strings.Repeat("s", 32), "goa_session", time.Hour, true},
+		{"short secret", "secret", "goa_session", time.Hour, false},
+		{"empty cookie name", "", "", time.Hour, false},
+		{"cookie name with separator", "", "goa session", time.Hour, false},
+		{"zero ttl", "", "goa_session", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t)
+			AppConfig.SessionSecret = tt.secret
+			AppConfig.SessionCookieName = tt.cookie
+			AppConfig.SessionTTL = tt.ttl
+
+			if err := validateSessionConfig(); (err == nil) != tt.valid {
+				t.Errorf("validateSessionConfig() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
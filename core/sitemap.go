@@ -0,0 +1,327 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sitemapMaxURLs   = 50000
+	seoCacheMaxHosts = 16
+)
+
+type RobotsRule struct {
+	UserAgent  string
+	Allow      []string
+	Disallow   []string
+	CrawlDelay int
+}
+
+type SitemapURL struct {
+	Path    string
+	LastMod time.Time
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name          `xml:"urlset"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	URLs    []sitemapURLEntry `xml:"url"`
+}
+
+type sitemapURLEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name            `xml:"sitemapindex"`
+	Xmlns    string              `xml:"xmlns,attr"`
+	Sitemaps []sitemapIndexEntry `xml:"sitemap"`
+}
+
+type sitemapIndexEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type seoCache struct {
+	mutex    sync.Mutex
+	loaded   time.Time
+	sitemaps map[string]map[string][]byte
+	robots   map[string][]byte
+}
+
+func (c *seoCache) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sitemaps = nil
+	c.robots = nil
+}
+
+func (c *seoCache) sync(loaded time.Time) {
+	if !loaded.Equal(c.loaded) || len(c.sitemaps) > seoCacheMaxHosts || len(c.robots) > seoCacheMaxHosts {
+		c.loaded = loaded
+		c.sitemaps = nil
+		c.robots = nil
+	}
+	if c.sitemaps == nil {
+		c.sitemaps = make(map[string]map[string][]byte)
+		c.robots = make(map[string][]byte)
+	}
+}
+
+func (r *Router) addSEORoutes() {
+	if !AppConfig.Sitemap {
+		return
+	}
+
+	r.AddRoute("/sitemap.xml", r.handleSitemap)
+	r.AddRoute("/sitemap-[page].xml", r.handleSitemap)
+	r.AddRoute("/robots.txt", r.handleRobots)
+
+	r.Logger.InfoLog.Printf("SEO routes registered: /sitemap.xml, /robots.txt")
+}
+
+func (r *Router) SitemapURLs() ([]SitemapURL, error) {
+	var urls []SitemapURL
+
+	for _, route := range r.Routes {
+		if !r.isPageRoute(route) || isErrorPageRoute(route.Path) {
+			continue
+		}
+
+		meta := r.Marley.PageMeta[route.Path]
+		if meta["sitemap"] == "false" || meta["noindex"] == "true" {
+			continue
+		}
+
+		paths, err := r.expandRoute(route)
+		if err != nil {
+			return nil, err
+		}
+
+		lastMod := r.pageModTime(route.Path)
+		for _, p := range paths {
+			urls = append(urls, SitemapURL{Path: (&url.URL{Path: p}).EscapedPath(), LastMod: lastMod})
+		}
+	}
+
+	sort.Slice(urls, func(i, j int) bool { return urls[i].Path < urls[j].Path })
+	return urls, nil
+}
+
+func (r *Router) SitemapFiles(baseURL string) (map[string][]byte, error) {
+	urls, err := r.SitemapURLs()
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	files := make(map[string][]byte)
+
+	if len(urls) <= sitemapMaxURLs {
+		content, err := encodeURLSet(baseURL, urls)
+		if err != nil {
+			return nil, err
+		}
+		files["sitemap.xml"] = content
+		return files, nil
+	}
+
+	index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for start, part := 0, 1; start < len(urls); start, part = start+sitemapMaxURLs, part+1 {
+		end := start + sitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		name := fmt.Sprintf("sitemap-%d.xml", part)
+		content, err := encodeURLSet(baseURL, urls[start:end])
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+		index.Sitemaps = append(index.Sitemaps, sitemapIndexEntry{Loc: baseURL + "/" + name})
+	}
+
+	content, err := encodeXML(index)
+	if err != nil {
+		return nil, err
+	}
+	files["sitemap.xml"] = content
+
+	return files, nil
+}
+
+func (r *Router) RobotsTxt(baseURL string) []byte {
+	var buf bytes.Buffer
+
+	rules := AppConfig.Robots
+	if len(rules) == 0 {
+		rules = []RobotsRule{{UserAgent: "*", Allow: []string{"/"}}}
+	}
+
+	for i, rule := range rules {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		userAgent := rule.UserAgent
+		if userAgent == "" {
+			userAgent = "*"
+		}
+		fmt.Fprintf(&buf, "User-agent: %s\n", userAgent)
+		for _, allow := range rule.Allow {
+			fmt.Fprintf(&buf, "Allow: %s\n", allow)
+		}
+		for _, disallow := range rule.Disallow {
+			fmt.Fprintf(&buf, "Disallow: %s\n", disallow)
+		}
+		if rule.CrawlDelay > 0 {
+			fmt.Fprintf(&buf, "Crawl-delay: %d\n", rule.CrawlDelay)
+		}
+	}
+
+	if baseURL != "" && AppConfig.Sitemap {
+		fmt.Fprintf(&buf, "\nSitemap: %s/sitemap.xml\n", strings.TrimSuffix(baseURL, "/"))
+	}
+
+	return buf.Bytes()
+}
+
+func (r *Router) cachedSitemapFiles(baseURL string) (map[string][]byte, error) {
+	r.seo.mutex.Lock()
+	defer r.seo.mutex.Unlock()
+
+	r.seo.sync(r.Marley.CacheStats().LastLoad)
+	if files, ok := r.seo.sitemaps[baseURL]; ok {
+		return files, nil
+	}
+
+	files, err := r.SitemapFiles(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	r.seo.sitemaps[baseURL] = files
+	return files, nil
+}
+
+func (r *Router) cachedRobotsTxt(baseURL string) []byte {
+	r.seo.mutex.Lock()
+	defer r.seo.mutex.Unlock()
+
+	r.seo.sync(r.Marley.CacheStats().LastLoad)
+	if content, ok := r.seo.robots[baseURL]; ok {
+		return content
+	}
+
+	content := r.RobotsTxt(baseURL)
+	r.seo.robots[baseURL] = content
+	return content
+}
+
+func (r *Router) handleSitemap(w http.ResponseWriter, req *http.Request) {
+	name := path.Base(normalizePath(req.URL.Path))
+	if name != "sitemap.xml" {
+		page, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "sitemap-"), ".xml"))
+		if err != nil || page < 1 || name != fmt.Sprintf("sitemap-%d.xml", page) {
+			r.serveErrorPage(w, req, http.StatusNotFound)
+			return
+		}
+	}
+
+	baseURL := seoBaseURL(req)
+	if baseURL == "" {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	files, err := r.cachedSitemapFiles(baseURL)
+	if err != nil {
+		r.Logger.ErrorLog.Printf("Failed to build sitemap: %v", err)
+		http.Error(w, "Failed to build sitemap", http.StatusInternalServerError)
+		return
+	}
+
+	content, ok := files[name]
+	if !ok {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write(content)
+}
+
+func (r *Router) handleRobots(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(r.cachedRobotsTxt(seoBaseURL(req)))
+}
+
+func (r *Router) isPageRoute(route Route) bool {
+	return !route.IsStatic && !route.IsAPI && r.Marley.HasTemplate(route.Path)
+}
+
+func (r *Router) pageModTime(route string) time.Time {
+	file, ok := r.Marley.PageFiles[route]
+	if !ok {
+		return time.Time{}
+	}
+
+	info, err := fs.Stat(AppConfig.SiteFS(), file)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func siteBaseURL(req *http.Request) string {
+	if AppConfig.BaseURL != "" {
+		return strings.TrimSuffix(AppConfig.BaseURL, "/")
+	}
+
+	return RequestScheme(req) + "://" + RequestHost(req)
+}
+
+func seoBaseURL(req *http.Request) string {
+	if AppConfig.BaseURL == "" && !AppConfig.DevMode {
+		return ""
+	}
+	return siteBaseURL(req)
+}
+
+func encodeURLSet(baseURL string, urls []SitemapURL) ([]byte, error) {
+	set := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, u := range urls {
+		entry := sitemapURLEntry{Loc: baseURL + u.Path}
+		if !u.LastMod.IsZero() {
+			entry.LastMod = u.LastMod.UTC().Format("2006-01-02")
+		}
+		set.URLs = append(set.URLs, entry)
+	}
+	return encodeXML(set)
+}
+
+func encodeXML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode sitemap: %w", err)
+	}
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newSitemapTestRouter(t *testing.T) (*Router, *int) {
+	t.Helper()
+	withTestSite(t, testSite(map[string]string{
+		"app/blog/[slug].html": `{{define "content"}}{{.Params.slug}}{{end}}`,
+	}))
+	AppConfig.Sitemap = true
+	AppConfig.BaseURL = "https://example.com"
+
+	router := newTestRouter(t)
+	calls := 0
+	router.ParamsProviders["/blog/[slug]"] = func() ([]map[string]string, error) {
+		calls++
+		return []map[string]string{{"slug": "hello"}}, nil
+	}
+	return router, &calls
+}
+
+func TestSitemapPages(t *testing.T) {
+	router, _ := newSitemapTestRouter(t)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/sitemap.xml", http.StatusOK},
+		{"/sitemap-1.xml", http.StatusNotFound},
+		{"/sitemap-0.xml", http.StatusNotFound},
+		{"/sitemap-01.xml", http.StatusNotFound},
+		{"/sitemap--1.xml", http.StatusNotFound},
+		{"/sitemap-abc.xml", http.StatusNotFound},
+		{"/robots.txt", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestSitemapCachedUntilReload(t *testing.T) {
+	router, calls := newSitemapTestRouter(t)
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+		return w.Body.String()
+	}
+
+	body := get()
+	if !strings.Contains(body, "<loc>https://example.com/blog/hello</loc>") {
+		t.Fatalf("sitemap is missing the expanded route:\n%s", body)
+	}
+	get()
+	if *calls != 1 {
+		t.Errorf("params provider called %d times for two requests, want 1", *calls)
+	}
+
+	if err := router.ReloadRoutes(); err != nil {
+		t.Fatalf("ReloadRoutes: %v", err)
+	}
+	get()
+	if *calls != 2 {
+		t.Errorf("params provider called %d times after reload, want 2", *calls)
+	}
+}
+
+func TestSitemapEscapesParams(t *testing.T) {
+	router, _ := newSitemapTestRouter(t)
+	router.ParamsProviders["/blog/[slug]"] = func() ([]map[string]string, error) {
+		return []map[string]string{{"slug": "café"}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil))
+	if want := "<loc>https://example.com/blog/caf%C3%A9</loc>"; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("sitemap is missing %s:\n%s", want, w.Body.String())
+	}
+}
+
+func TestSitemapWithoutBaseURL(t *testing.T) {
+	router, _ := newSitemapTestRouter(t)
+	AppConfig.BaseURL = ""
+
+	req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+	req.Host = "evil.example"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("sitemap status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+	req.Host = "evil.example"
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), "evil.example") {
+		t.Errorf("robots.txt uses the request host:\n%s", w.Body.String())
+	}
+}
+
+func TestSEOBaseURL(t *testing.T) {
+	withTestConfig(t)
+
+	tests := []struct {
+		name    string
+		baseURL string
+		devMode bool
+		want    string
+	}{
+		{"configured", "https://example.com/", false, "https://example.com"},
+		{"configured in dev", "https://example.com", true, "https://example.com"},
+		{"request host in dev", "", true, "http://evil.example"},
+		{"no request host in production", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AppConfig.BaseURL = tt.baseURL
+			AppConfig.DevMode = tt.devMode
+			AppConfig.TrustedProxies = nil
+			req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+			req.Host = "evil.example"
+			if got := seoBaseURL(req); got != tt.want {
+				t.Errorf("seoBaseURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
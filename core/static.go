@@ -0,0 +1,258 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+type StaticMount struct {
+	Prefix      string
+	Dir         string
+	SPAFallback string
+	Middleware  []MiddlewareFunc
+}
+
+func (r *Router) StaticMounts() []StaticMount {
+	mounts := []StaticMount{{Prefix: "/static/", Dir: r.StaticDir}}
+	return append(mounts, AppConfig.StaticMounts...)
+}
+
+func (r *Router) addStaticMount(mount StaticMount, primary bool) {
+	prefix := staticMountPrefix(mount.Prefix)
+	if prefix == "/" {
+		r.Logger.ErrorLog.Printf("Static mount for %s cannot use the root prefix", mount.Dir)
+		return
+	}
+
+	staticFS, err := AppConfig.SubFS(mount.Dir)
+	if err != nil {
+		r.Logger.ErrorLog.Printf("Failed to open static directory %s: %v", mount.Dir, err)
+		return
+	}
+
+	mc := NewMiddlewareChain()
+	for _, m := range mount.Middleware {
+		mc.Use(m)
+	}
+
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		r.serveStaticFile(w, req, staticFS, mount, strings.TrimPrefix(req.URL.Path, prefix))
+	}
+	if primary {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			name := strings.TrimPrefix(req.URL.Path, prefix)
+			if original, ok := r.Marley.AssetManifest().Resolve(name); ok {
+				w.Header().Set("Cache-Control", immutableCacheControl)
+				name = original
+			}
+			if isBlockedStaticPath(name) {
+				r.serveErrorPage(w, req, http.StatusNotFound)
+				return
+			}
+			if r.serveBundle(w, req, name) {
+				return
+			}
+			if isImageRequest(req, name) {
+				r.serveImage(w, req, staticFS, name)
+				return
+			}
+			r.serveStaticFile(w, req, staticFS, mount, name)
+		}
+	}
+
+	r.Routes = append(r.Routes, Route{
+		Path:       prefix,
+		Handler:    handler,
+		IsStatic:   true,
+		Middleware: mc,
+	})
+
+	r.Logger.InfoLog.Printf("Static route registered: %s → %s", prefix, mount.Dir)
+}
+
+func (r *Router) matchStaticRoute(requestPath string) (Route, bool) {
+	var match Route
+	found := false
+	for _, route := range r.Routes {
+		if !route.IsStatic {
+			continue
+		}
+		if requestPath != strings.TrimSuffix(route.Path, "/") && !strings.HasPrefix(requestPath, route.Path) {
+			continue
+		}
+		if !found || len(route.Path) > len(match.Path) {
+			match = route
+			found = true
+		}
+	}
+	return match, found
+}
+
+func (r *Router) serveStaticFile(w http.ResponseWriter, req *http.Request, staticFS fs.FS, mount StaticMount, name string) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := staticMountPrefix(mount.Prefix)
+	if req.URL.Path == strings.TrimSuffix(prefix, "/") {
+		http.Redirect(w, req, prefix, http.StatusMovedPermanently)
+		return
+	}
+
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+	if isBlockedStaticPath(name) {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	info, err := fs.Stat(staticFS, name)
+	if err == nil && info.IsDir() {
+		if !strings.HasSuffix(req.URL.Path, "/") {
+			http.Redirect(w, req, req.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		index := path.Join(name, "index.html")
+		if indexInfo, indexErr := fs.Stat(staticFS, index); indexErr == nil && !indexInfo.IsDir() {
+			name, info = index, indexInfo
+		} else if AppConfig.StaticListing {
+			r.serveStaticListing(w, req, staticFS, name)
+			return
+		} else {
+			err = fs.ErrNotExist
+		}
+	}
+
+	if err != nil && mount.SPAFallback != "" && path.Ext(name) == "" && acceptsHTML(req) {
+		name = strings.TrimPrefix(path.Clean("/"+mount.SPAFallback), "/")
+		info, err = fs.Stat(staticFS, name)
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	if err != nil || info.IsDir() {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	servedName, encoding := precompressedSibling(w, req, staticFS, name, info)
+	file, err := staticFS.Open(servedName)
+	if err != nil {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			r.Logger.ErrorLog.Printf("Failed to read static file %s: %v", name, err)
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	setStaticCacheControl(w, name)
+	http.ServeContent(w, req, name, info.ModTime(), content)
+}
+
+func (r *Router) serveStaticListing(w http.ResponseWriter, req *http.Request, staticFS fs.FS, dir string) {
+	entries, err := fs.ReadDir(staticFS, dir)
+	if err != nil {
+		r.serveErrorPage(w, req, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<pre>\n")
+	for _, entry := range entries {
+		name := entry.Name()
+		if isBlockedStaticPath(path.Join(dir, name)) {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		link := url.URL{Path: name}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", html.EscapeString(link.String()), html.EscapeString(name))
+	}
+	fmt.Fprintf(w, "</pre>\n")
+}
+
+func isBlockedStaticPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." && segment != ".well-known" {
+			return true
+		}
+	}
+
+	for _, pattern := range AppConfig.StaticBlockedPatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, path.Base(name)); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setStaticCacheControl(w http.ResponseWriter, name string) {
+	if w.Header().Get("Cache-Control") != "" {
+		return
+	}
+
+	if AppConfig.DevMode {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+
+	if cacheControl, ok := AppConfig.StaticCacheControl[strings.ToLower(path.Ext(name))]; ok {
+		w.Header().Set("Cache-Control", cacheControl)
+		return
+	}
+	if AppConfig.StaticDefaultCacheControl != "" {
+		w.Header().Set("Cache-Control", AppConfig.StaticDefaultCacheControl)
+	}
+}
+
+func staticMountPrefix(prefix string) string {
+	prefix = path.Clean("/" + prefix)
+	if prefix != "/" {
+		prefix += "/"
+	}
+	return prefix
+}
+
+func staticMountFor(route string) string {
+	mounts := append([]StaticMount{{Prefix: "/static/"}}, AppConfig.StaticMounts...)
+	for _, mount := range mounts {
+		prefix := staticMountPrefix(mount.Prefix)
+		if route == strings.TrimSuffix(prefix, "/") || strings.HasPrefix(route, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+func acceptsHTML(req *http.Request) bool {
+	accept := req.Header.Get("Accept")
+	return accept == "" || strings.Contains(accept, "text/html") || strings.Contains(accept, "*/*")
+}
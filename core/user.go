@@ -0,0 +1,86 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const userSessionKey = "_user"
+
+type User struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name,omitempty"`
+	Email       string                 `json:"email,omitempty"`
+	Roles       []string               `json:"roles,omitempty"`
+	Permissions []string               `json:"permissions,omitempty"`
+	Claims      map[string]interface{} `json:"claims,omitempty"`
+}
+
+func GetUser(r *http.Request) *User {
+	if user := sessionUser(r); user != nil {
+		return user
+	}
+
+	if claims := GetClaims(r); claims != nil {
+		user := userFromClaims(claims)
+		user.Permissions = strings.Fields(claims.String("scope"))
+		if scp, ok := claims["scp"].([]interface{}); ok {
+			for _, s := range scp {
+				if value, ok := s.(string); ok {
+					user.Permissions = append(user.Permissions, value)
+				}
+			}
+		}
+		return user
+	}
+
+	if credential := GetCredential(r); credential != nil {
+		return &User{ID: credential.ID, Permissions: credential.Scopes}
+	}
+	return nil
+}
+
+func sessionUser(r *http.Request) *User {
+	session := GetSession(r)
+	if session == nil {
+		return nil
+	}
+
+	switch value := session.Get(userSessionKey).(type) {
+	case *User:
+		return value
+	case map[string]interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil
+		}
+		var user User
+		if err := json.Unmarshal(data, &user); err != nil || user.ID == "" {
+			return nil
+		}
+		return &user
+	}
+	return nil
+}
+
+func LoginUser(r *http.Request, user *User) bool {
+	session := GetSession(r)
+	if session == nil {
+		return false
+	}
+
+	session.Rotate()
+	session.Set(userSessionKey, user)
+	return true
+}
+
+func LogoutUser(r *http.Request) {
+	if session := GetSession(r); session != nil {
+		session.Destroy()
+	}
+}
+
+func (u *User) HasRole(role string) bool {
+	return u != nil && containsString(u.Roles, role)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -13,21 +15,60 @@ type FileWatcher struct {
 	router        *Router
 	watcher       *fsnotify.Watcher
 	debounceTimer *time.Timer
+	fileTimers    map[string]*time.Timer
 	dirs          []string
 	logger        *AppLogger
 }
 
+var (
+	watchedFilesMutex sync.Mutex
+	watchedFiles      = make(map[string]func() error)
+)
+
+func watchFile(file string, reload func() error) {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+
+	watchedFilesMutex.Lock()
+	defer watchedFilesMutex.Unlock()
+	watchedFiles[file] = reload
+}
+
+func hasWatchedFiles() bool {
+	watchedFilesMutex.Lock()
+	defer watchedFilesMutex.Unlock()
+	return len(watchedFiles) > 0
+}
+
+func watchedFile(name string) (string, func() error, bool) {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+
+	watchedFilesMutex.Lock()
+	defer watchedFilesMutex.Unlock()
+	reload, ok := watchedFiles[name]
+	return name, reload, ok
+}
+
 func NewFileWatcher(router *Router, logger *AppLogger) (*FileWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file watcher: %w", err)
 	}
 
+	var dirs []string
+	if AppConfig.DevMode && AppConfig.LiveReload {
+		dirs = []string{AppConfig.AppDir, AppConfig.StaticDir}
+	}
+
 	return &FileWatcher{
-		router:  router,
-		watcher: watcher,
-		dirs:    []string{AppConfig.AppDir, AppConfig.StaticDir},
-		logger:  logger,
+		router:     router,
+		watcher:    watcher,
+		fileTimers: make(map[string]*time.Timer),
+		dirs:       dirs,
+		logger:     logger,
 	}, nil
 }
 
@@ -43,6 +84,17 @@ func (fw *FileWatcher) Start() {
 		}
 	}
 
+	watchedFilesMutex.Lock()
+	for file := range watchedFiles {
+		dir := filepath.Dir(file)
+		if err := fw.watcher.Add(dir); err != nil {
+			fw.logger.ErrorLog.Printf("Error watching %s: %v", file, err)
+		} else {
+			fw.logger.InfoLog.Printf("Watching file: %s", file)
+		}
+	}
+	watchedFilesMutex.Unlock()
+
 	go fw.watchLoop()
 }
 
@@ -62,13 +114,22 @@ func (fw *FileWatcher) watchLoop() {
 			if !ok {
 				return
 			}
+			if file, reload, ok := watchedFile(event.Name); ok {
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					fw.reloadFile(file, reload, debounceTimeout)
+				}
+				continue
+			}
+			if !fw.inWatchedDir(event.Name) {
+				continue
+			}
 			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) {
 				if fw.debounceTimer != nil {
 					fw.debounceTimer.Stop()
 				}
 				fw.debounceTimer = time.AfterFunc(debounceTimeout, func() {
 					fw.logger.InfoLog.Printf("File change detected in %s, reloading...", event.Name)
-					err := fw.router.InitRoutes()
+					err := fw.router.ReloadRoutes()
 					if err != nil {
 						fw.logger.ErrorLog.Printf("Failed to reload templates: %v", err)
 					} else {
@@ -85,6 +146,30 @@ func (fw *FileWatcher) watchLoop() {
 	}
 }
 
+func (fw *FileWatcher) reloadFile(file string, reload func() error, debounceTimeout time.Duration) {
+	if timer, ok := fw.fileTimers[file]; ok {
+		timer.Stop()
+	}
+	fw.fileTimers[file] = time.AfterFunc(debounceTimeout, func() {
+		if err := reload(); err != nil {
+			fw.logger.ErrorLog.Printf("Failed to reload %s, keeping the previous version: %v", file, err)
+		} else {
+			fw.logger.InfoLog.Printf("Reloaded %s", file)
+		}
+	})
+}
+
+func (fw *FileWatcher) inWatchedDir(name string) bool {
+	name = filepath.Clean(name)
+	for _, dir := range fw.dirs {
+		dir = filepath.Clean(dir)
+		if name == dir || strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (fw *FileWatcher) watchDir(dir string) error {
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -2,6 +2,17 @@ module goalandingpage
 
 go 1.21
 
-require github.com/fsnotify/fsnotify v1.9.0
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/klauspost/compress v1.17.11
+	github.com/tdewolff/minify/v2 v2.21.3
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/crypto v0.27.0
+	golang.org/x/sync v0.8.0
+	golang.org/x/term v0.24.0
+)
 
-require golang.org/x/sys v0.13.0 // indirect
+require (
+	github.com/tdewolff/parse/v2 v2.7.19 // indirect
+	golang.org/x/sys v0.25.0 // indirect
+)
@@ -1,17 +1,44 @@
 package main
 
 import (
+	"bufio"
+	"embed"
 	"flag"
+	"fmt"
 	"goalandingpage/core"
+	"io"
 	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
 )
 
+//go:embed app static all:public
+var siteFS embed.FS
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "hash":
+			runHash(os.Args[2:])
+			return
+		}
+	}
 
 	port := flag.String("port", core.AppConfig.Port, "Port to run the server on")
+	dev := flag.Bool("dev", core.AppConfig.DevMode, "Serve app and static files from disk with live reload")
 	flag.Parse()
 
 	core.AppConfig.Port = *port
+	core.AppConfig.DevMode = *dev
+	core.AppConfig.FS = siteFS
 
 	app := core.NewApp()
 
@@ -25,3 +52,92 @@ func main() {
 		log.Fatalf("Go on Airplanes server error: %v", err)
 	}
 }
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "dist", "Directory to write the static site to")
+	precompress := fs.Bool("precompress", core.AppConfig.Precompress, "Write .gz and .zst copies of compressible files")
+	fs.Parse(args)
+
+	core.AppConfig.LiveReload = false
+	core.AppConfig.Precompress = *precompress
+	core.AppConfig.FS = siteFS
+
+	app := core.NewApp()
+
+	err := app.Init()
+	if err != nil {
+		log.Fatalf("Failed to initialize Go on Airplanes: %v", err)
+	}
+
+	_, err = app.Export(*out)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "Exit with a non-zero status on warnings too")
+	fs.Parse(args)
+
+	core.AppConfig.FS = siteFS
+
+	report, err := core.CheckApp()
+	if err != nil {
+		log.Fatalf("Check failed: %v", err)
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+	fmt.Printf("Checked %d files: %d errors, %d warnings\n", report.Files, report.Errors(), report.Warnings())
+
+	if report.Errors() > 0 {
+		os.Exit(1)
+	}
+	if *strict && report.Warnings() > 0 {
+		os.Exit(2)
+	}
+}
+
+func runHash(args []string) {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	password := fs.Bool("password", false, "Hash a Basic auth password with bcrypt instead of an API key with SHA-256")
+	fs.Parse(args)
+
+	if fs.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: hash [-password] < secret.txt")
+		os.Exit(2)
+	}
+
+	secret, err := readSecret()
+	if err != nil {
+		log.Fatalf("Failed to read secret: %v", err)
+	}
+	if secret == "" {
+		log.Fatalf("Secret must not be empty")
+	}
+
+	hash, err := core.HashCredential(secret, *password)
+	if err != nil {
+		log.Fatalf("Hash failed: %v", err)
+	}
+	fmt.Println(hash)
+}
+
+func readSecret() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Secret: ")
+		secret, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(secret), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}